@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// altService is an alternative endpoint advertised by an Alt-Svc header
+// (RFC 7838). An empty host means the host of the request.
+type altService struct {
+	protocol string // ALPN ID such as h3 or h2.
+	host     string
+	port     string
+}
+
+func (s altService) String() string {
+	return s.protocol + "=" + net.JoinHostPort(s.host, s.port)
+}
+
+// parseAltSvc parses the values of the Alt-Svc headers of a response,
+// skipping malformed entries. "clear", which withdraws every earlier
+// advertisement, and empty values yield no services.
+func parseAltSvc(values []string) []altService {
+	var services []altService
+	for _, value := range values {
+		if strings.TrimSpace(value) == "clear" {
+			return nil
+		}
+		for _, entry := range strings.Split(value, ",") {
+			alternative, _, _ := strings.Cut(entry, ";")
+			protocol, authority, ok := strings.Cut(strings.TrimSpace(alternative), "=")
+			if !ok || protocol == "" {
+				continue
+			}
+			host, port, err := net.SplitHostPort(strings.Trim(strings.TrimSpace(authority), `"`))
+			if err != nil || port == "" {
+				continue
+			}
+			services = append(services, altService{protocol: protocol, host: host, port: port})
+		}
+	}
+	return services
+}
+
+// formatAltSvc renders services as protocol=host:port strings for output.
+func formatAltSvc(services []altService) []string {
+	if len(services) == 0 {
+		return nil
+	}
+	out := make([]string, len(services))
+	for i, s := range services {
+		out[i] = s.String()
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// apexWriter writes the registrable domain of each survivor once, giving the
+// -group-apex list of distinct organizations rather than hostnames.
+type apexWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+	seen   map[string]bool
+}
+
+func newApexWriter(path string) (*apexWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &apexWriter{file: file, writer: bufio.NewWriter(file), seen: make(map[string]bool)}, nil
+}
+
+// add writes the registrable domain of host unless it was written before.
+func (w *apexWriter) add(host string) error {
+	apex := registrableDomain(host)
+	if apex == "" || w.seen[apex] {
+		return nil
+	}
+	w.seen[apex] = true
+	_, err := fmt.Fprintln(w.writer, apex)
+	return err
+}
+
+func (w *apexWriter) Close() error {
+	if err := w.writer.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// registrableDomain returns the registrable domain (eTLD+1) of host, or the
+// bare host for IP addresses and names without a public suffix.
+func registrableDomain(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.Trim(host, "[]."))
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// bandwidthMeter counts the bytes sent and received on every connection the
+// scanner dials, including TLS and proxy overhead, for the end-of-scan report
+// and -max-bandwidth.
+type bandwidthMeter struct {
+	sent, received atomic.Int64
+
+	limit    int64  // Total bytes after which onLimit is called; 0 is unlimited.
+	onLimit  func() // Stops the scan.
+	once     sync.Once
+	exceeded atomic.Bool
+}
+
+// bandwidth meters all scan traffic.
+var bandwidth = &bandwidthMeter{}
+
+// meter wraps a dial function so its connections are counted.
+func (m *bandwidthMeter) meter(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &meteredConn{Conn: conn, m: m}, nil
+	}
+}
+
+func (m *bandwidthMeter) total() int64 {
+	return m.sent.Load() + m.received.Load()
+}
+
+// check stops the scan the first time the total passes the limit.
+func (m *bandwidthMeter) check() {
+	if m.limit > 0 && m.total() > m.limit {
+		m.once.Do(func() {
+			m.exceeded.Store(true)
+			fmt.Fprintf(console, "Bandwidth limit of %s reached; stopping the scan\n", formatBytes(m.limit))
+			if m.onLimit != nil {
+				m.onLimit()
+			}
+		})
+	}
+}
+
+type meteredConn struct {
+	net.Conn
+	m *bandwidthMeter
+}
+
+func (c *meteredConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.m.received.Add(int64(n))
+	c.m.check()
+	return n, err
+}
+
+func (c *meteredConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.m.sent.Add(int64(n))
+	c.m.check()
+	return n, err
+}
+
+// formatBytes renders n in the largest binary unit that keeps it above 1,
+// e.g. 1.5 MB.
+func formatBytes(n int64) string {
+	const unit = 1 << 10
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "KB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// checkBaseline compares a matching response with the host's answer for a
+// random path that cannot exist, recording the check in v. Under -baseline-mode
+// differs, the default, a page survives when its similarity to the baseline
+// is below -baseline-threshold, weeding out catch-all pages and soft 404s;
+// under matches it survives when the similarity is at or above it. The check
+// is skipped, and the page kept, when no usable baseline can be fetched.
+func checkBaseline(client *http.Client, t target, resp *http.Response, body []byte, v *verdict) bool {
+	baseline, err := fetchBaseline(client, t, resp)
+	if memBudget != nil {
+		defer memBudget.release(int64(len(baseline)))
+	}
+	if err != nil {
+		return v.add("baseline", true, "skipped, fetch failed: "+err.Error())
+	}
+	if len(strings.Fields(string(baseline))) == 0 {
+		// Nothing to compare against: any page would look completely different.
+		return v.add("baseline", true, "skipped, empty baseline body")
+	}
+	similarity := bodySimilarity(body, baseline)
+	if baselineMatches {
+		return v.add("baseline", similarity >= baselineThreshold,
+			fmt.Sprintf("similarity %.2f, want >= %.2f", similarity, baselineThreshold))
+	}
+	return v.add("baseline", similarity < baselineThreshold,
+		fmt.Sprintf("similarity %.2f, want < %.2f", similarity, baselineThreshold))
+}
+
+// fetchBaseline requests a random path on the host that served resp, with
+// the same headers as the probe.
+func fetchBaseline(client *http.Client, t target, resp *http.Response) ([]byte, error) {
+	token := make([]byte, 12)
+	scanRand.Read(token)
+	u := *resp.Request.URL
+	u.Path = "/" + hex.EncodeToString(token)
+	u.RawPath, u.RawQuery, u.Fragment = "", "", ""
+
+	req, err := http.NewRequestWithContext(scanCtx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	setRequestHeaders(req)
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	req.Host = resp.Request.Host
+	baseline, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer baseline.Body.Close()
+	return readExtraBody(baseline)
+}
+
+// bodySimilarity is the Jaccard similarity, from 0 to 1, of the sets of
+// whitespace-separated tokens in a and b.
+func bodySimilarity(a, b []byte) float64 {
+	tokensA := make(map[string]bool)
+	for _, tok := range strings.Fields(string(a)) {
+		tokensA[tok] = true
+	}
+	tokensB := make(map[string]bool)
+	for _, tok := range strings.Fields(string(b)) {
+		tokensB[tok] = true
+	}
+	if len(tokensA) == 0 && len(tokensB) == 0 {
+		return 1
+	}
+	shared := 0
+	for tok := range tokensA {
+		if tokensB[tok] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(tokensA)+len(tokensB)-shared)
+}
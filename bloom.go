@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// deduper reports whether an input line has been seen before, recording it if not.
+type deduper interface {
+	seen(key string) bool
+}
+
+// newDeduper returns the deduper selected by -dedup, or nil when dedup is disabled.
+func newDeduper(mode string, fpRate float64) (deduper, error) {
+	switch mode {
+	case "":
+		return nil, nil
+	case "exact":
+		return exactDeduper{}, nil
+	case "bloom":
+		if fpRate <= 0 || fpRate >= 1 {
+			return nil, fmt.Errorf("bloom false-positive rate must be between 0 and 1, got %v", fpRate)
+		}
+		return newScalableBloom(fpRate), nil
+	default:
+		return nil, fmt.Errorf("unknown dedup mode %q (expected exact or bloom)", mode)
+	}
+}
+
+// exactDeduper remembers every key in a map. It never drops a unique line,
+// but memory grows with the number of distinct lines.
+type exactDeduper map[string]struct{}
+
+func (d exactDeduper) seen(key string) bool {
+	if _, ok := d[key]; ok {
+		return true
+	}
+	d[key] = struct{}{}
+	return false
+}
+
+// bloomFilter is a fixed-capacity bloom filter using double hashing.
+type bloomFilter struct {
+	bits     []uint64
+	m, k     uint64
+	count    uint64
+	capacity uint64
+}
+
+func newBloomFilter(capacity uint64, fpRate float64) *bloomFilter {
+	// Optimal sizing: m = -n ln(p) / ln(2)^2 bits and k = (m/n) ln(2) hashes.
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Max(1, math.Round(float64(m)/float64(capacity)*math.Ln2)))
+	return &bloomFilter{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: capacity,
+	}
+}
+
+// bloomHashes returns the two base hashes combined as h1 + i*h2 to derive k positions.
+func bloomHashes(key string) (uint64, uint64) {
+	h := fnv.New128a()
+	h.Write([]byte(key))
+	sum := h.Sum(nil)
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
+
+func (f *bloomFilter) contains(h1, h2 uint64) bool {
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *bloomFilter) add(h1, h2 uint64) {
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+	f.count++
+}
+
+// scalableBloom is a scalable bloom filter: when the current filter reaches
+// capacity, a larger one with a tighter false-positive rate is appended, so
+// the compound false-positive rate stays below the configured bound no
+// matter how many lines are read.
+type scalableBloom struct {
+	filters []*bloomFilter
+	fpRate  float64
+}
+
+const (
+	bloomInitialCapacity = 1 << 20
+	bloomGrowth          = 2   // Capacity multiplier for each new filter.
+	bloomTightening      = 0.5 // False-positive multiplier for each new filter.
+)
+
+func newScalableBloom(fpRate float64) *scalableBloom {
+	b := &scalableBloom{fpRate: fpRate}
+	b.grow()
+	return b
+}
+
+// grow appends a filter. Filter i gets rate p*(1-r)*r^i, which sums to at most p.
+func (b *scalableBloom) grow() {
+	n := len(b.filters)
+	capacity := uint64(bloomInitialCapacity) * uint64(math.Pow(bloomGrowth, float64(n)))
+	rate := b.fpRate * (1 - bloomTightening) * math.Pow(bloomTightening, float64(n))
+	b.filters = append(b.filters, newBloomFilter(capacity, rate))
+}
+
+func (b *scalableBloom) seen(key string) bool {
+	h1, h2 := bloomHashes(key)
+	for _, f := range b.filters {
+		if f.contains(h1, h2) {
+			return true
+		}
+	}
+	last := b.filters[len(b.filters)-1]
+	if last.count >= last.capacity {
+		b.grow()
+		last = b.filters[len(b.filters)-1]
+	}
+	last.add(h1, h2)
+	return false
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// saveBody writes body to a file in dir named after host. The port is kept
+// in the name so endpoints on one host stay apart, and a numeric suffix is
+// added when the name is already taken, e.g. by a repeated input line.
+func saveBody(dir, host string, body []byte) (string, error) {
+	base := sanitizeFilename(host)
+	for i := 0; ; i++ {
+		name := base + ".body"
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d.body", base, i)
+		}
+		path := filepath.Join(dir, name)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = file.Write(body)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return path, err
+	}
+}
+
+// sanitizeFilename maps host[:port] to a safe file name, replacing anything
+// but letters, digits, dots and hyphens with an underscore.
+func sanitizeFilename(host string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, host)
+	name = strings.Trim(name, ".")
+	if name == "" {
+		name = "_"
+	}
+	return name
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// cachedOutcome is the memoized evaluation of a URL within a run.
+type cachedOutcome struct {
+	statusCode int
+	bodyHash   string
+	finalURL   string // Where the URL led after redirects.
+	matched    bool
+	page       pageState
+	score      int
+	headers    map[string][]string
+	altSvc     []string
+	title      string
+
+	responseTimeMs float64
+	slow           bool
+	proto, http3   string
+	technologies   []string
+	san            string
+	caseVariant    string
+	bodyFile       string
+}
+
+// responseCache is a thread-safe LRU cache of evaluation outcomes keyed by URL.
+// Outcomes are stored under both the requested and the final URL, so later
+// targets that request the same URL, or redirect to an already evaluated
+// one, short-circuit instead of fetching again.
+type responseCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // Front is most recently used.
+	items    map[string]*list.Element
+}
+
+type responseCacheItem struct {
+	key     string
+	outcome cachedOutcome
+}
+
+func newResponseCache(capacity int) *responseCache {
+	return &responseCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *responseCache) get(key string) (cachedOutcome, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return cachedOutcome{}, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*responseCacheItem).outcome, true
+}
+
+func (c *responseCache) add(key string, outcome cachedOutcome) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*responseCacheItem).outcome = outcome
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&responseCacheItem{key: key, outcome: outcome})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*responseCacheItem).key)
+	}
+}
+
+// cacheableKey marks a request context as eligible for the response cache.
+// Requests with a custom Host header or SNI are excluded, since the same URL
+// may serve different content for them.
+type cacheableKey struct{}
+
+func withCacheable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheableKey{}, true)
+}
+
+func isCacheable(ctx context.Context) bool {
+	return ctx.Value(cacheableKey{}) != nil
+}
+
+// cachedRedirectError stops a redirect whose destination is already cached,
+// carrying the cached outcome back to fetchURL.
+type cachedRedirectError struct {
+	url     string
+	outcome cachedOutcome
+}
+
+func (e *cachedRedirectError) Error() string {
+	return "redirect to already evaluated " + e.url
+}
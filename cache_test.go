@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCachedResultMatchesLive checks that a survivor served from the
+// response cache is reported with the same fields as when fetched live.
+func TestCachedResultMatchesLive(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/admin" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Server", "nginx/1.25.3")
+		fmt.Fprint(w, "<html><title>Admin</title>welcome</html>")
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	oldClient, oldResponses, oldPath := httpClient, responses, requestPath
+	oldSignatures, oldCaseProbe, oldBodies := signatures, caseProbe, saveBodiesDir
+	defer func() {
+		httpClient, responses, requestPath = oldClient, oldResponses, oldPath
+		signatures, caseProbe, saveBodiesDir = oldSignatures, oldCaseProbe, oldBodies
+	}()
+	httpClient = getHTTPClient(5*time.Second, false)
+	responses = newResponseCache(16)
+	requestPath = "/admin"
+	signatures, _ = loadSignatures("")
+	caseProbe = true
+	saveBodiesDir = t.TempDir()
+
+	probeOnce := func() Result {
+		var result Result
+		if matched, _ := probeHTTP(target{raw: host, host: host, scheme: "http"}, &result, singleStatus(http.StatusOK), false); !matched {
+			t.Fatalf("no match: %+v", result)
+		}
+		return result
+	}
+	live := probeOnce()
+	if len(live.Technologies) == 0 || live.CaseVariant == "" || live.BodyFile == "" {
+		t.Fatalf("live result lacks the fields under test: %+v", live)
+	}
+	before := requests.Load()
+	cached := probeOnce()
+	if requests.Load() != before {
+		t.Fatal("second probe was not served from the cache")
+	}
+	if !reflect.DeepEqual(cached, live) {
+		t.Errorf("cached result differs from the live one:\ncached %+v\nlive   %+v", cached, live)
+	}
+}
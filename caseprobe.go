@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"unicode"
+)
+
+// caseProbeSimilarity is the body similarity below which -case-probe reports
+// a randomly cased path as answering differently from the original.
+const caseProbeSimilarity = 0.9
+
+// randomCase returns path with the case of its letters flipped at random,
+// at least one of them changed, or "" when path has no letters to flip.
+func randomCase(path string) string {
+	runes := []rune(path)
+	var letters []int
+	for i, r := range runes {
+		if unicode.ToUpper(r) != unicode.ToLower(r) {
+			letters = append(letters, i)
+		}
+	}
+	if len(letters) == 0 {
+		return ""
+	}
+	flip := func(i int) {
+		if unicode.IsUpper(runes[i]) {
+			runes[i] = unicode.ToLower(runes[i])
+		} else {
+			runes[i] = unicode.ToUpper(runes[i])
+		}
+	}
+	flipped := false
+	for _, i := range letters {
+		if scanRand.Int63n(2) == 0 {
+			flip(i)
+			flipped = true
+		}
+	}
+	if !flipped {
+		flip(letters[scanRand.Int63n(int64(len(letters)))])
+	}
+	return string(runes)
+}
+
+// probeCase requests a randomly cased variant of the path that produced
+// resp and body (the final one, after redirects), with the same headers,
+// and describes how its answer differs: "/AdMiN:404" for another status,
+// "/AdMiN:body" for the same status with a different body. It returns ""
+// when the two answers agree, the path has no letters, or the variant
+// cannot be fetched.
+func probeCase(client *http.Client, t target, resp *http.Response, body []byte) string {
+	u := *resp.Request.URL
+	variant := randomCase(u.Path)
+	if variant == "" {
+		return ""
+	}
+	u.Path, u.RawPath = variant, ""
+
+	req, err := http.NewRequestWithContext(scanCtx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return ""
+	}
+	setRequestHeaders(req)
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	req.Host = resp.Request.Host
+	caseResp, err := client.Do(req)
+	if err != nil {
+		if scanCtx.Err() == nil {
+			fmt.Fprintf(console, "Error fetching case variant %s: %v\n", u.String(), err)
+		}
+		return ""
+	}
+	defer caseResp.Body.Close()
+	if caseResp.StatusCode != resp.StatusCode {
+		return fmt.Sprintf("%s:%d", variant, caseResp.StatusCode)
+	}
+	caseBody, _ := readExtraBody(caseResp)
+	if memBudget != nil {
+		defer memBudget.release(int64(len(caseBody)))
+	}
+	if bodySimilarity(body, caseBody) < caseProbeSimilarity {
+		return variant + ":body"
+	}
+	return ""
+}
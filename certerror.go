@@ -0,0 +1,37 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+)
+
+// isCertError reports whether err is a failure to verify the server's
+// certificate chain.
+func isCertError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var invalid x509.CertificateInvalidError
+	var unknown x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	return errors.As(err, &verifyErr) || errors.As(err, &invalid) || errors.As(err, &unknown) || errors.As(err, &hostname)
+}
+
+// certErrorCategory names the reason a certificate chain was rejected:
+// cert-expired (or not yet valid), cert-unknown-authority for self-signed
+// certificates and missing intermediates, cert-hostname-mismatch, or
+// cert-invalid for anything else.
+func certErrorCategory(err error) string {
+	var invalid x509.CertificateInvalidError
+	var unknown x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	switch {
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		return "cert-expired"
+	case errors.As(err, &unknown):
+		return "cert-unknown-authority"
+	case errors.As(err, &hostname):
+		return "cert-hostname-mismatch"
+	default:
+		return "cert-invalid"
+	}
+}
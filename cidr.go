@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// maxCIDRHostBits is the largest range expanded without -allow-large-cidr:
+// a /16 in IPv4, 65,536 addresses.
+const maxCIDRHostBits = 16
+
+// parseCIDR returns the prefix of an input line in CIDR notation, such as
+// 10.0.0.0/24. ok is false for any other line.
+func parseCIDR(line string) (prefix netip.Prefix, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.Contains(line, "/") {
+		return netip.Prefix{}, false
+	}
+	prefix, err := netip.ParsePrefix(line)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return prefix.Masked(), true
+}
+
+// cidrHostBits is the number of host bits in prefix.
+func cidrHostBits(prefix netip.Prefix) int {
+	return prefix.Addr().BitLen() - prefix.Bits()
+}
+
+// cidrSize returns how many addresses expandCIDR yields for prefix, capped
+// at the largest int for huge IPv6 ranges.
+func cidrSize(prefix netip.Prefix) int {
+	bits := cidrHostBits(prefix)
+	if bits >= 62 {
+		return int(^uint(0) >> 1)
+	}
+	n := 1 << bits
+	if prefix.Addr().Is4() && bits >= 2 {
+		n -= 2 // Network and broadcast addresses.
+	}
+	return n
+}
+
+// expandCIDR calls fn with each address in prefix until fn returns false.
+// The network and broadcast addresses of IPv4 ranges larger than /31 are
+// skipped, since they do not belong to hosts.
+func expandCIDR(prefix netip.Prefix, fn func(ip string) bool) {
+	skipEnds := prefix.Addr().Is4() && cidrHostBits(prefix) >= 2
+	addr := prefix.Addr()
+	if skipEnds {
+		addr = addr.Next()
+	}
+	for ; addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		if skipEnds && !prefix.Contains(addr.Next()) {
+			break
+		}
+		if !fn(addr.String()) {
+			return
+		}
+	}
+}
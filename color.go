@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ANSI colors for interactive runs.
+const (
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorReset  = "\x1b[0m"
+)
+
+// console is where progress and error messages go: standard output, or
+// standard error when survivors own standard output ("-o -"). Under -tui on
+// a terminal it is the dashboard, which shows them in a pane of its own. It
+// is set before the scan starts and never changes while it runs.
+var console io.Writer = os.Stdout
+
+// consoleColor enables colored progress messages, set when they go to a terminal.
+var consoleColor bool
+
+// colorSupported reports whether f is a terminal and colors were not turned
+// off with -no-color or the NO_COLOR convention.
+func colorSupported(f *os.File, noColor bool) bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(f.Fd()))
+}
+
+// colorize wraps s in color, or returns it unchanged when color is "".
+func colorize(color, s string) string {
+	if color == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// printColored prints a progress message line in color when consoleColor is set.
+func printColored(color, format string, args ...any) {
+	if !consoleColor {
+		color = ""
+	}
+	fmt.Fprintln(console, colorize(color, fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// compareRuns returns the domains in the newer output file that are not in
+// the older one (added) and those that dropped out of it (removed), both
+// sorted. Plain and -json outputs can be mixed.
+func compareRuns(oldPath, newPath string) (added, removed []string, err error) {
+	before, err := readResultDomains(oldPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := readResultDomains(newPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	for domain := range after {
+		if !before[domain] {
+			added = append(added, domain)
+		}
+	}
+	for domain := range before {
+		if !after[domain] {
+			removed = append(removed, domain)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed, nil
+}
+
+// readResultDomains reads the set of domains in an output file: the first
+// field of plain lines after any -timestamps stamp, without a -show-scheme
+// prefix, or the domain of JSON records.
+func readResultDomains(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	domains := make(map[string]bool)
+	scanner := newLineReader(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "{") {
+			var r Result
+			if err := json.Unmarshal([]byte(line), &r); err != nil || r.Domain == "" {
+				return nil, fmt.Errorf("invalid JSON record in %s: %q", path, line)
+			}
+			domains[r.Domain] = true
+			continue
+		}
+		fields := strings.Fields(line)
+		if _, err := time.Parse(time.RFC3339, fields[0]); err == nil && len(fields) > 1 {
+			fields = fields[1:]
+		}
+		domain := fields[0]
+		if _, rest, ok := strings.Cut(domain, "://"); ok {
+			domain = rest
+		}
+		domains[domain] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return domains, nil
+}
+
+// printComparison prints the result of compareRuns.
+func printComparison(added, removed []string) {
+	fmt.Fprintf(console, "Newly surviving (%d):\n", len(added))
+	for _, domain := range added {
+		fmt.Fprintln(console, "  "+domain)
+	}
+	fmt.Fprintf(console, "Newly dead (%d):\n", len(removed))
+	for _, domain := range removed {
+		fmt.Fprintln(console, "  "+domain)
+	}
+}
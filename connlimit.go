@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// connSlots caps the sockets open at once under -max-open-conns; nil when
+// unlimited. A slot is taken before dialing and given back when the
+// connection is closed.
+var connSlots chan struct{}
+
+// limitConns makes dial wait for a free connSlots slot.
+func limitConns(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		select {
+		case connSlots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			<-connSlots
+			return nil, err
+		}
+		return &slotConn{Conn: conn}, nil
+	}
+}
+
+// slotConn gives its connSlots slot back once closed.
+type slotConn struct {
+	net.Conn
+	once sync.Once
+}
+
+func (c *slotConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() { <-connSlots })
+	return err
+}
+
+// ephemeralPorts returns how many local ports outgoing connections can use:
+// the Linux ip_local_port_range, or the common default of 28232 elsewhere.
+func ephemeralPorts() int {
+	data, err := os.ReadFile("/proc/sys/net/ipv4/ip_local_port_range")
+	if err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) == 2 {
+			low, err1 := strconv.Atoi(fields[0])
+			high, err2 := strconv.Atoi(fields[1])
+			if err1 == nil && err2 == nil && high >= low {
+				return high - low + 1
+			}
+		}
+	}
+	return 28232
+}
+
+// warnPortExhaustion warns when -new_connection at this concurrency is
+// likely to run out of local ports. Every closed connection keeps its port
+// in TIME_WAIT for about a minute, so a connection per second and socket
+// held open is assumed.
+func warnPortExhaustion(workers int) {
+	concurrency := workers
+	if connSlots != nil && cap(connSlots) < concurrency {
+		concurrency = cap(connSlots)
+	}
+	if ports := ephemeralPorts(); concurrency*60 > ports {
+		fmt.Fprintf(console, "Warning: %d concurrent connections with -new_connection may exhaust the %d local ports "+
+			"(each closed connection holds its port for about a minute); lower -t or set -max-open-conns "+
+			"if connections fail with \"cannot assign requested address\" (ports-exhausted).\n", concurrency, ports)
+	}
+}
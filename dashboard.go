@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// dashboardLines is how many recent survivors and messages -tui shows.
+const dashboardLines = 8
+
+// dashboard is the -tui live view of a scan: throughput, dead domains by
+// error, proxy health and the latest survivors. On a terminal it is redrawn
+// in place every second, with progress messages shown in a pane of their own
+// instead of scrolling past. Elsewhere it degrades to a plain progress line
+// every ten seconds.
+type dashboard struct {
+	started time.Time
+	out     *os.File // Where the dashboard is drawn.
+	tty     bool
+
+	probed    atomic.Int64
+	survivors atomic.Int64
+	dead      atomic.Int64
+
+	mu       sync.Mutex
+	errors   map[string]int
+	recent   []string                // Latest survivors, newest last.
+	messages []string                // Latest progress messages, newest last.
+	partial  []byte                  // Start of a message line not yet complete.
+	capture  bool                    // Messages are kept for the pane instead of printed.
+	proxies  map[string]*proxyHealth // By proxy host:port.
+
+	stopTick chan struct{}
+	tickDone chan struct{}
+}
+
+// proxyHealth counts the connections opened to a proxy.
+type proxyHealth struct {
+	name       string
+	ok, failed int
+}
+
+// newDashboard returns a dashboard drawn on out, redrawn in place when
+// interactive is set and out is a terminal.
+func newDashboard(out *os.File, interactive bool) *dashboard {
+	d := &dashboard{
+		started: time.Now(),
+		out:     out,
+		tty:     interactive && term.IsTerminal(int(out.Fd())),
+		errors:  make(map[string]int),
+		proxies: make(map[string]*proxyHealth),
+	}
+	for _, entry := range proxies {
+		if proxyURL, err := parseProxyURL(entry); err == nil {
+			d.proxies[proxyURL.Host] = &proxyHealth{name: proxyURL.Redacted()}
+		}
+	}
+	return d
+}
+
+// start begins drawing. On a terminal, the progress messages it is sent as
+// console land in the dashboard instead of breaking its layout.
+func (d *dashboard) start() {
+	interval := 10 * time.Second
+	if d.tty {
+		interval = time.Second
+		d.mu.Lock()
+		d.capture = true
+		d.mu.Unlock()
+	}
+	d.stopTick = make(chan struct{})
+	d.tickDone = make(chan struct{})
+	go func() {
+		defer close(d.tickDone)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.draw()
+			case <-d.stopTick:
+				return
+			}
+		}
+	}()
+}
+
+// stop draws the final state and lets messages through again, so the
+// summary is printed normally below the dashboard.
+func (d *dashboard) stop() {
+	close(d.stopTick)
+	<-d.tickDone
+	d.mu.Lock()
+	d.capture = false
+	d.mu.Unlock()
+	d.draw()
+}
+
+// Write takes the progress messages sent to console. While the dashboard
+// is drawn in place, their latest lines are kept for its messages pane;
+// otherwise they are printed on its output.
+func (d *dashboard) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.capture {
+		return d.out.Write(p)
+	}
+	d.partial = append(d.partial, p...)
+	for {
+		line, rest, ok := bytes.Cut(d.partial, []byte("\n"))
+		if !ok {
+			break
+		}
+		d.messages = appendRecent(d.messages, string(line))
+		d.partial = rest
+	}
+	return len(p), nil
+}
+
+// addSurvivor records a survivor written to the output.
+func (d *dashboard) addSurvivor(domain string) {
+	d.survivors.Add(1)
+	d.mu.Lock()
+	d.recent = appendRecent(d.recent, domain)
+	d.mu.Unlock()
+}
+
+// addDead records a domain that did not respond, by error category.
+func (d *dashboard) addDead(category string) {
+	d.dead.Add(1)
+	d.mu.Lock()
+	d.errors[category]++
+	d.mu.Unlock()
+}
+
+// dialed records the outcome of a connection to addr, if it is a proxy.
+func (d *dashboard) dialed(addr string, err error) {
+	health, ok := d.proxies[addr]
+	if !ok {
+		return
+	}
+	d.mu.Lock()
+	if err != nil {
+		health.failed++
+	} else {
+		health.ok++
+	}
+	d.mu.Unlock()
+}
+
+// appendRecent appends line to lines, keeping the last dashboardLines.
+func appendRecent(lines []string, line string) []string {
+	lines = append(lines, line)
+	if len(lines) > dashboardLines {
+		lines = lines[len(lines)-dashboardLines:]
+	}
+	return lines
+}
+
+func (d *dashboard) draw() {
+	elapsed := time.Since(d.started)
+	probed := d.probed.Load()
+	rate := float64(probed) / elapsed.Seconds()
+	if !d.tty {
+		fmt.Fprintf(d.out, "Progress: %s probed (%.1f/s), %d survivors, %d dead, %s transferred, %v elapsed\n",
+			formatCount(int(probed)), rate, d.survivors.Load(), d.dead.Load(),
+			formatBytes(bandwidth.total()), elapsed.Round(time.Second))
+		return
+	}
+
+	width := 120
+	if w, _, err := term.GetSize(int(d.out.Fd())); err == nil && w > 0 {
+		width = w
+	}
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		s := fmt.Sprintf(format, args...)
+		if len(s) > width {
+			s = s[:width]
+		}
+		b.WriteString(s + "\x1b[K\n")
+	}
+
+	d.mu.Lock()
+	b.WriteString("\x1b[H\x1b[2J")
+	line("DomainSurvivor - %v elapsed%s", elapsed.Round(time.Second), pausedLabel())
+	line("")
+	line("  Probed       %s (%.1f/s)", formatCount(int(probed)), rate)
+	line("  Survivors    %s", formatCount(int(d.survivors.Load())))
+	line("  Dead         %s", formatCount(int(d.dead.Load())))
+	line("  Transferred  %s", formatBytes(bandwidth.total()))
+	if len(d.errors) > 0 {
+		line("")
+		line("Dead by error:")
+		categories := make([]string, 0, len(d.errors))
+		for category := range d.errors {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+		for _, category := range categories {
+			line("  %-20s %d", category, d.errors[category])
+		}
+	}
+	if len(d.proxies) > 0 {
+		line("")
+		line("Proxies:%32s %8s", "connected", "failed")
+		addrs := make([]string, 0, len(d.proxies))
+		for addr := range d.proxies {
+			addrs = append(addrs, addr)
+		}
+		sort.Strings(addrs)
+		for _, addr := range addrs {
+			health := d.proxies[addr]
+			line("  %-38s %8d %8d", health.name, health.ok, health.failed)
+		}
+	}
+	line("")
+	line("Recent survivors:")
+	for _, domain := range d.recent {
+		line("  %s", domain)
+	}
+	if len(d.messages) > 0 {
+		line("")
+		line("Messages:")
+		for _, message := range d.messages {
+			line("  %s", message)
+		}
+	}
+	d.mu.Unlock()
+	fmt.Fprint(d.out, b.String())
+}
+
+// pausedLabel marks the dashboard header while the scan is paused.
+func pausedLabel() string {
+	scanPause.mu.Lock()
+	defer scanPause.mu.Unlock()
+	if scanPause.paused {
+		return " (paused)"
+	}
+	return ""
+}
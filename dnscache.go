@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dnsCacheSweepEvery is how many inserts pass between sweeps of expired entries.
+const dnsCacheSweepEvery = 10000
+
+// dnsCache caches system resolver lookups for a fixed TTL, since the system
+// resolver does not report record TTLs. It is shared by all workers, and
+// concurrent lookups of the same host wait for a single query. Hosts that do
+// not exist are cached too; other failures are retried on the next lookup.
+type dnsCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*dnsCacheEntry
+	inserts int
+}
+
+// dnsCacheEntry is a cached lookup. ready is closed once the lookup finishes.
+type dnsCacheEntry struct {
+	ready   chan struct{}
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{ttl: ttl, entries: make(map[string]*dnsCacheEntry)}
+}
+
+// lookupHost returns the addresses of host from the cache or the system resolver.
+func (c *dnsCache) lookupHost(ctx context.Context, host string) ([]string, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	if ok {
+		select {
+		case <-entry.ready:
+			if time.Now().After(entry.expires) {
+				ok = false
+			}
+		default: // Still being looked up.
+		}
+	}
+	if !ok {
+		entry = &dnsCacheEntry{ready: make(chan struct{})}
+		c.entries[host] = entry
+		c.inserts++
+		if c.inserts%dnsCacheSweepEvery == 0 {
+			c.sweep()
+		}
+	}
+	c.mu.Unlock()
+
+	if ok {
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return entry.addrs, entry.err
+	}
+
+	entry.addrs, entry.err = net.DefaultResolver.LookupHost(ctx, host)
+	entry.expires = time.Now().Add(c.ttl)
+	var dnsErr *net.DNSError
+	if entry.err != nil && !(errors.As(entry.err, &dnsErr) && dnsErr.IsNotFound) {
+		entry.expires = time.Time{} // Expired at once, so the next lookup retries.
+	}
+	close(entry.ready)
+	return entry.addrs, entry.err
+}
+
+// sweep removes expired entries. The caller holds c.mu.
+func (c *dnsCache) sweep() {
+	now := time.Now()
+	for host, entry := range c.entries {
+		select {
+		case <-entry.ready:
+			if now.After(entry.expires) {
+				delete(c.entries, host)
+			}
+		default:
+		}
+	}
+}
+
+// dialResolved wraps dial so hostnames are resolved with lookup and each
+// returned address is tried in turn. IP literals are dialed directly.
+func dialResolved(lookup func(ctx context.Context, host string) ([]string, error),
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+		addrs, err := lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error
+		for _, ip := range addrs {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
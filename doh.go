@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dohResolver resolves hostnames over DNS-over-HTTPS (RFC 8484), bypassing
+// the system resolver, and caches answers for their TTL.
+type dohResolver struct {
+	endpoint string
+	client   *http.Client
+
+	mu    sync.Mutex
+	cache map[string]dohCacheEntry
+}
+
+type dohCacheEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func newDoHResolver(endpoint string, timeout time.Duration) *dohResolver {
+	return &dohResolver{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+		cache:    make(map[string]dohCacheEntry),
+	}
+}
+
+// lookupHost returns the addresses of host, trying A records before AAAA.
+func (r *dohResolver) lookupHost(ctx context.Context, host string) ([]string, error) {
+	host = strings.TrimSuffix(host, ".")
+
+	r.mu.Lock()
+	entry, ok := r.cache[host]
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	var lastErr error
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		addrs, ttl, err := r.query(ctx, host, qtype)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(addrs) == 0 {
+			continue
+		}
+		r.mu.Lock()
+		r.cache[host] = dohCacheEntry{addrs: addrs, expires: time.Now().Add(ttl)}
+		r.mu.Unlock()
+		return addrs, nil
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// query sends a single DoH POST request and returns the addresses in the
+// answer along with the smallest TTL among them.
+func (r *dohResolver) query(ctx context.Context, host string, qtype dnsmessage.Type) ([]string, time.Duration, error) {
+	name, err := dnsmessage.NewName(host + ".")
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid hostname %q: %v", host, err)
+	}
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(packed))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("DoH query for %s failed: %v", host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("DoH query for %s failed: status %d", host, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var answer dnsmessage.Message
+	if err := answer.Unpack(body); err != nil {
+		return nil, 0, fmt.Errorf("invalid DoH response for %s: %v", host, err)
+	}
+	if answer.RCode == dnsmessage.RCodeNameError {
+		return nil, 0, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	var addrs []string
+	var minTTL uint32
+	for _, rr := range answer.Answers {
+		var ip net.IP
+		switch res := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			ip = res.A[:]
+		case *dnsmessage.AAAAResource:
+			ip = res.AAAA[:]
+		default:
+			continue // CNAMEs are followed by the resolver; only addresses matter here.
+		}
+		addrs = append(addrs, ip.String())
+		if minTTL == 0 || rr.Header.TTL < minTTL {
+			minTTL = rr.Header.TTL
+		}
+	}
+	return addrs, time.Duration(minTTL) * time.Second, nil
+}
+
+// dialContext wraps dial so hostnames are resolved through DoH.
+func (r *dohResolver) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return dialResolved(r.lookupHost, dial)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// pageState is what -only-changed compares between runs for a survivor.
+type pageState struct {
+	Status   int    `json:"status,omitempty"`
+	BodyHash string `json:"body_sha256,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
+
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// pageTitle returns the text of the first <title> in body, with whitespace
+// collapsed and entities decoded, or "" when there is none.
+func pageTitle(body []byte) string {
+	m := titlePattern.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+}
+
+// driftStore is the -drift-db file of the page state each survivor had on
+// the previous runs, keyed like the output deduplication. It is loaded at
+// startup and written back once the scan completes.
+type driftStore struct {
+	mu     sync.Mutex
+	path   string
+	states map[string]pageState
+	fresh  bool // No database existed yet, so every survivor is new.
+
+	changed, unchanged int
+}
+
+func loadDriftStore(path string) (*driftStore, error) {
+	s := &driftStore{path: path, states: make(map[string]pageState)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.fresh = true
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.states); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// update stores state for key and returns whether it differs from the
+// stored one. A key seen for the first time counts as changed.
+func (s *driftStore) update(key string, state pageState) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.states[key]
+	s.states[key] = state
+	if ok && old == state {
+		s.unchanged++
+		return false
+	}
+	s.changed++
+	return true
+}
+
+// forget drops key, so the survivor is reported again once it comes back.
+func (s *driftStore) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+}
+
+// save writes the store back through a temporary file, so an interrupted
+// write never leaves a truncated database behind.
+func (s *driftStore) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.MarshalIndent(s.states, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
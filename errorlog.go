@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// errorLogger writes classified fetch and connect errors to the -error-log
+// file instead of the console, one tab-separated line per error: time,
+// domain, category, URL or address, and the error message.
+type errorLogger struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	count  int
+}
+
+func newErrorLogger(path string, appendMode bool) (*errorLogger, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &errorLogger{file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+func (l *errorLogger) record(domain, category, target string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.count++
+	fmt.Fprintf(l.writer, "%s\t%s\t%s\t%s\t%v\n", time.Now().Format(time.RFC3339), domain, category, target, err)
+}
+
+func (l *errorLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.writer.Flush(); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
+
+// reportError sends a classified error for t to the -error-log file when
+// set, or prints it in red otherwise. action names what failed, e.g.
+// "Error fetching", and targetDesc the URL or address it failed on.
+func reportError(t target, category, targetDesc, action string, err error) {
+	if errorLog != nil {
+		errorLog.record(t.raw, category, targetDesc, err)
+		return
+	}
+	printColored(colorRed, "%s %s (%s): %v", action, targetDesc, category, err)
+}
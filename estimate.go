@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// printEstimate counts the lines of the input files and, when sampleSize > 0,
+// probes that many targets from the start of the input to project the scan
+// duration for the given number of workers. The files are rewound afterwards.
+// Inputs that are not regular files (pipes, devices) cannot be read twice, so
+// the estimate is skipped if there are any. Under -sample only the sampled
+// share is counted. With warmUp, the sample is warmed up first so the timing
+// excludes cold DNS and connection setup.
+func printEstimate(files []*os.File, parse func(string) (target, error), sampleSize, workers int, allowLargeCIDR bool,
+	sample *sampler, targetStatus statusSet, checkAlive, warmUp bool) error {
+	for _, file := range files {
+		info, err := file.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+	}
+
+	total := 0
+	var lines []string
+	for _, file := range files {
+		n, fileLines, err := countLines(file, sampleSize-len(lines), allowLargeCIDR, sample)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		total += n
+		lines = append(lines, fileLines...)
+	}
+	if total == 0 || len(lines) == 0 {
+		fmt.Fprintf(console, "Estimate: %s domains.\n", formatCount(total))
+		return nil
+	}
+
+	if warmUp {
+		warmUpSample(lines, parse, workers)
+	}
+	perTarget := benchmarkSample(lines, parse, workers, targetStatus, checkAlive)
+	perTarget += requestDelay + delayJitter/2
+	projected := time.Duration(float64(perTarget) * float64(total) / float64(workers))
+	fmt.Fprintf(console, "Estimate: %s domains, ~%v at current settings (%v per domain over a %d-domain sample, %d workers).\n",
+		formatCount(total), roundEstimate(projected), perTarget.Round(time.Millisecond), len(lines), workers)
+	return nil
+}
+
+// countLines counts the targets in r, counting each address of the CIDR
+// ranges that will be expanded, and returns the first sampleSize lines.
+// With a -sample sampler, only the lines it keeps are counted, and CIDR
+// ranges by their expected share.
+func countLines(r io.Reader, sampleSize int, allowLargeCIDR bool, keep *sampler) (int, []string, error) {
+	var sample []string
+	total := 0
+	scanner := newLineReader(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if prefix, ok := parseCIDR(line); ok {
+			if allowLargeCIDR || cidrHostBits(prefix) <= maxCIDRHostBits {
+				size := cidrSize(prefix)
+				if keep != nil {
+					size = int(float64(size) * keep.percent / 100)
+				}
+				total += size
+			}
+			continue
+		}
+		if keep != nil && !keep.keep(line) {
+			continue
+		}
+		total++
+		if len(sample) < sampleSize {
+			sample = append(sample, line)
+		}
+	}
+	return total, sample, scanner.Err()
+}
+
+// benchmarkSample probes the sample with up to workers goroutines and returns
+// the average time spent per target. Results are discarded, and the probe
+// features that leave traces are off meanwhile: -save-bodies files, the
+// response cache, proxy ban accounting, -trace timings and error messages.
+// The scan probes the sample again and must find it as if it had not been.
+func benchmarkSample(sample []string, parse func(string) (target, error), workers int,
+	targetStatus statusSet, checkAlive bool) time.Duration {
+	savedBodiesDir, savedResponses, savedCooldown, savedTracer, savedConsole := saveBodiesDir, responses, proxyCooldown, tracer, console
+	saveBodiesDir, responses, proxyCooldown, tracer, console = "", nil, 0, nil, io.Discard
+	defer func() {
+		saveBodiesDir, responses, proxyCooldown, tracer, console = savedBodiesDir, savedResponses, savedCooldown, savedTracer, savedConsole
+	}()
+
+	lines := make(chan string)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var spent time.Duration
+	for i := 0; i < min(workers, len(sample)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range lines {
+				t, err := parse(line)
+				if err != nil {
+					continue
+				}
+				start := time.Now()
+				var result Result
+				probe(t, &result, targetStatus, checkAlive)
+				mu.Lock()
+				spent += time.Since(start)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, line := range sample {
+		lines <- line
+	}
+	close(lines)
+	wg.Wait()
+	return spent / time.Duration(len(sample))
+}
+
+// warmUpSample sends a HEAD request to every endpoint of the sample with up
+// to workers goroutines, filling the DNS cache and leaving kept-alive
+// connections in the pool for the timed probes. Responses are discarded.
+func warmUpSample(sample []string, parse func(string) (target, error), workers int) {
+	start := time.Now()
+	lines := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < min(workers, len(sample)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range lines {
+				t, err := parse(line)
+				if err != nil {
+					continue
+				}
+				client := getSNIClient(t.sni)
+				for _, ep := range httpEndpoints(t) {
+					// h3 endpoints are https URLs fetched over QUIC, as in probeHTTP.
+					scheme, epClient := ep.protocol, client
+					if ep.protocol == "h3" {
+						scheme, epClient = "https", getHTTP3Client(t.sni)
+					}
+					req, err := http.NewRequestWithContext(scanCtx, http.MethodHead, scheme+"://"+ep.host+requestPath, nil)
+					if err != nil {
+						continue
+					}
+					setRequestHeaders(req)
+					if t.hostHeader != "" {
+						req.Host = t.hostHeader
+					}
+					resp, err := epClient.Do(req)
+					if err != nil {
+						continue
+					}
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+			}
+		}()
+	}
+	for _, line := range sample {
+		lines <- line
+	}
+	close(lines)
+	wg.Wait()
+	fmt.Fprintf(console, "Warmed up %d domains in %v.\n", len(sample), time.Since(start).Round(time.Millisecond))
+}
+
+// formatCount renders n with thousands separators, e.g. 12,340,000.
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// roundEstimate rounds d to a precision that suits its size.
+func roundEstimate(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Hour:
+		return d.Round(time.Minute)
+	case d >= time.Minute:
+		return d.Round(time.Second)
+	default:
+		return d.Round(100 * time.Millisecond)
+	}
+}
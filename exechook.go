@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// execBodyExcerpt is how much of the body is passed to the -exec command.
+const execBodyExcerpt = 64 << 10
+
+// execHook runs the -exec command for responses, bounded to its own number of
+// concurrent runs so slow scripts do not multiply with the HTTP workers.
+type execHook struct {
+	args    []string
+	slots   chan struct{}
+	timeout time.Duration
+}
+
+// execInput is the JSON document written to the command's stdin.
+type execInput struct {
+	URL     string              `json:"url"`
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers"`
+	Body    string              `json:"body"`
+}
+
+// newExecHook splits command on whitespace; it is run directly, not through a shell.
+func newExecHook(command string, concurrency int, timeout time.Duration) (*execHook, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("-exec needs a command")
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, fmt.Errorf("-exec command: %v", err)
+	}
+	return &execHook{args: args, slots: make(chan struct{}, max(concurrency, 1)), timeout: timeout}, nil
+}
+
+// match pipes the response to the command and reports whether it exited
+// with status 0, along with a detail for -explain.
+func (h *execHook) match(resp *http.Response, body []byte) (bool, string) {
+	if len(body) > execBodyExcerpt {
+		body = body[:execBodyExcerpt]
+	}
+	input, err := json.Marshal(execInput{
+		URL:     resp.Request.URL.String(),
+		Status:  resp.StatusCode,
+		Headers: resp.Header,
+		Body:    string(body),
+	})
+	if err != nil {
+		return false, err.Error()
+	}
+
+	h.slots <- struct{}{}
+	defer func() { <-h.slots }()
+
+	ctx, cancel := context.WithTimeout(scanCtx, h.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, h.args[0], h.args[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return true, "exit 0"
+	case errors.As(err, &exitErr) && ctx.Err() == nil:
+		return false, fmt.Sprintf("exit %d", exitErr.ExitCode())
+	default:
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %v", h.timeout)
+		}
+		fmt.Fprintf(console, "Error running -exec for %s: %v %s\n", resp.Request.URL, err, strings.TrimSpace(stderr.String()))
+		return false, err.Error()
+	}
+}
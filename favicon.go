@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxFaviconBytes caps the favicon download for -favicon-hash.
+const maxFaviconBytes = 1 << 20
+
+// fetchFaviconHash fetches /favicon.ico relative to pageURL and returns its
+// Shodan-style hash. ok is false when the host has no usable favicon.
+func fetchFaviconHash(client *http.Client, pageURL, hostHeader string) (hash int32, ok bool, err error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return 0, false, err
+	}
+	faviconURL := base.ResolveReference(&url.URL{Path: "/favicon.ico"}).String()
+
+	req, err := http.NewRequest(http.MethodGet, faviconURL, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	setRequestHeaders(req)
+	// Let the transport negotiate compression so the body arrives decoded.
+	req.Header.Del("Accept-Encoding")
+	if hostHeader != "" {
+		req.Host = hostHeader
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to fetch favicon: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, false, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFaviconBytes))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read favicon: %v", err)
+	}
+	// Servers often answer unknown paths with an HTML page, so require
+	// either an image Content-Type or image content.
+	if len(data) == 0 || (!strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") &&
+		!strings.HasPrefix(http.DetectContentType(data), "image/")) {
+		return 0, false, nil
+	}
+	return faviconHash(data), true, nil
+}
+
+// faviconHash computes the hash Shodan uses for http.favicon.hash: the
+// signed 32-bit MurmurHash3 of the base64 encoding with a newline after
+// every 76 characters and at the end, as Python's base64.encodebytes does.
+func faviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteByte('\n')
+	return int32(murmur3(0, []byte(b.String())))
+}
+
+// murmur3 is the 32-bit x86 variant of MurmurHash3.
+func murmur3(seed uint32, data []byte) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+
+	h := seed
+	nblocks := len(data) / 4
+	for i := 0; i < nblocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	tail := data[nblocks*4:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// signature identifies a technology from response headers, cookies and body.
+// Any matching part is enough. A header pattern of "" only requires the
+// header to be present. When a pattern's first capture group matches, it is
+// reported as the version, e.g. "nginx/1.25.3".
+type signature struct {
+	Name    string            `json:"name"`
+	Headers map[string]string `json:"headers"`
+	Cookies []string          `json:"cookies"`
+	Body    []string          `json:"body"`
+
+	headers map[string]*regexp.Regexp
+	body    []*regexp.Regexp
+}
+
+// builtinSignatures is the default -fingerprint signature set.
+var builtinSignatures = []signature{
+	{Name: "nginx", Headers: map[string]string{"Server": `(?i)nginx(?:/([\d.]+))?`}},
+	{Name: "Apache", Headers: map[string]string{"Server": `(?i)apache(?:/([\d.]+))?`}},
+	{Name: "IIS", Headers: map[string]string{"Server": `(?i)microsoft-iis(?:/([\d.]+))?`}},
+	{Name: "LiteSpeed", Headers: map[string]string{"Server": `(?i)litespeed`}},
+	{Name: "Caddy", Headers: map[string]string{"Server": `(?i)caddy`}},
+	{Name: "Tomcat", Headers: map[string]string{"Server": `(?i)tomcat`}, Body: []string{`Apache Tomcat(?:/([\d.]+))?`}},
+	{Name: "Cloudflare", Headers: map[string]string{"CF-Ray": "", "Server": `(?i)^cloudflare$`}},
+	{Name: "Akamai", Headers: map[string]string{"Server": `(?i)akamaighost`}},
+	{Name: "AmazonS3", Headers: map[string]string{"Server": `(?i)^AmazonS3$`}},
+	{Name: "CloudFront", Headers: map[string]string{"X-Amz-Cf-Id": ""}},
+	{Name: "Varnish", Headers: map[string]string{"X-Varnish": ""}},
+	{Name: "PHP", Headers: map[string]string{"X-Powered-By": `(?i)php(?:/([\d.]+))?`}, Cookies: []string{"PHPSESSID"}},
+	{Name: "ASP.NET", Headers: map[string]string{"X-AspNet-Version": `(.+)`, "X-Powered-By": `(?i)asp\.net`}, Cookies: []string{"ASP.NET_SessionId"}},
+	{Name: "Express", Headers: map[string]string{"X-Powered-By": `(?i)^express$`}},
+	{Name: "Next.js", Headers: map[string]string{"X-Powered-By": `(?i)next\.js`}, Body: []string{`/_next/static/`}},
+	{Name: "Java", Cookies: []string{"JSESSIONID"}},
+	{Name: "Laravel", Cookies: []string{"laravel_session"}},
+	{Name: "Django", Cookies: []string{"csrftoken"}, Body: []string{`csrfmiddlewaretoken`}},
+	{Name: "WordPress", Body: []string{`/wp-content/`, `<meta name="generator" content="WordPress ?([\d.]+)?`}},
+	{Name: "Drupal", Headers: map[string]string{"X-Drupal-Cache": "", "X-Generator": `(?i)drupal(?: ([\d.]+))?`}},
+	{Name: "Joomla", Body: []string{`<meta name="generator" content="Joomla`}},
+	{Name: "Shopify", Headers: map[string]string{"X-ShopId": ""}, Body: []string{`cdn\.shopify\.com`}},
+	{Name: "Jenkins", Headers: map[string]string{"X-Jenkins": `(.+)`}},
+	{Name: "GitLab", Body: []string{`<meta content="GitLab" property="og:site_name">`}},
+	{Name: "Grafana", Body: []string{`<title>Grafana</title>`}},
+	{Name: "Kibana", Headers: map[string]string{"Kbn-Name": ""}},
+	{Name: "phpMyAdmin", Body: []string{`<title>phpMyAdmin`}},
+	{Name: "jQuery", Body: []string{`jquery[.-]([\d.]+?)(?:\.min)?\.js`}},
+}
+
+// loadSignatures compiles the built-in signatures plus any from a JSON file
+// holding an array of signatures in the same shape.
+func loadSignatures(path string) ([]signature, error) {
+	sigs := append([]signature(nil), builtinSignatures...)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signatures: %v", err)
+		}
+		var custom []signature
+		if err := json.Unmarshal(data, &custom); err != nil {
+			return nil, fmt.Errorf("invalid signatures file: %v", err)
+		}
+		sigs = append(sigs, custom...)
+	}
+	for i := range sigs {
+		sig := &sigs[i]
+		if sig.Name == "" {
+			return nil, fmt.Errorf("signature %d has no name", i+1)
+		}
+		sig.headers = make(map[string]*regexp.Regexp)
+		for name, pattern := range sig.Headers {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("signature %s: %v", sig.Name, err)
+			}
+			sig.headers[name] = re
+		}
+		for _, pattern := range sig.Body {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("signature %s: %v", sig.Name, err)
+			}
+			sig.body = append(sig.body, re)
+		}
+	}
+	return sigs, nil
+}
+
+// fingerprint returns the technologies whose signatures match the response.
+func fingerprint(sigs []signature, resp *http.Response, body []byte) []string {
+	var techs []string
+	for _, sig := range sigs {
+		if matched, version := sig.match(resp, body); matched {
+			if version != "" {
+				techs = append(techs, sig.Name+"/"+version)
+			} else {
+				techs = append(techs, sig.Name)
+			}
+		}
+	}
+	return techs
+}
+
+// match reports whether any part of sig matches, with the version if captured.
+func (sig *signature) match(resp *http.Response, body []byte) (bool, string) {
+	matched := false
+	version := ""
+	check := func(m []string) {
+		if m == nil {
+			return
+		}
+		matched = true
+		if len(m) > 1 && m[1] != "" && version == "" {
+			version = m[1]
+		}
+	}
+	for name, re := range sig.headers {
+		values := resp.Header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		if re.String() == "" {
+			matched = true
+			continue
+		}
+		for _, value := range values {
+			check(re.FindStringSubmatch(value))
+		}
+	}
+	for _, cookie := range resp.Cookies() {
+		for _, name := range sig.Cookies {
+			if strings.EqualFold(cookie.Name, name) {
+				matched = true
+			}
+		}
+	}
+	for _, re := range sig.body {
+		if m := re.FindSubmatch(body); m != nil {
+			strs := make([]string, len(m))
+			for i := range m {
+				strs[i] = string(m[i])
+			}
+			check(strs)
+		}
+	}
+	return matched, version
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxBodyBytes caps how much of a response body is read by every feature
+// that looks at bodies, so one huge response cannot exhaust memory. Set with
+// -max-body-bytes.
+var maxBodyBytes int64 = 2 << 20
+
+// readBody reads up to maxBodyBytes of the decoded response body. Bodies are
+// only left compressed when Accept-Encoding was set explicitly, in which case
+// gzip and deflate are decoded here. Under -mem-budget the body's size stays
+// reserved until the caller releases len(body) bytes.
+func readBody(resp *http.Response) ([]byte, error) {
+	if memBudget != nil {
+		return readReserved(resp, memBudget.acquire)
+	}
+	return readDecodedBody(resp)
+}
+
+// readExtraBody is readBody for a body fetched while the worker still holds
+// another, as the baseline and case probes do. Its reservation does not wait
+// for room: two workers each holding a body and waiting for more would wait
+// on each other forever.
+func readExtraBody(resp *http.Response) ([]byte, error) {
+	if memBudget != nil {
+		return readReserved(resp, memBudget.take)
+	}
+	return readDecodedBody(resp)
+}
+
+func readReserved(resp *http.Response, reserve func(int64)) ([]byte, error) {
+	reserved := expectedBodySize(resp)
+	reserve(reserved)
+	body, err := readDecodedBody(resp)
+	memBudget.release(reserved - int64(len(body)))
+	return body, err
+}
+
+func readDecodedBody(resp *http.Response) ([]byte, error) {
+	var body io.Reader = resp.Body
+	if !resp.Uncompressed {
+		switch strings.ToLower(resp.Header.Get("Content-Encoding")) {
+		case "gzip":
+			gz, err := gzip.NewReader(resp.Body)
+			if err != nil {
+				return nil, err
+			}
+			defer gz.Close()
+			body = gz
+		case "deflate":
+			zr, err := zlib.NewReader(resp.Body)
+			if err != nil {
+				return nil, err
+			}
+			defer zr.Close()
+			body = zr
+		}
+	}
+	return io.ReadAll(io.LimitReader(body, maxBodyBytes))
+}
+
+// hashBody returns the hex SHA-256 of body.
+func hashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadBaselineHashes reads "domain hash" lines, the plain output format of
+// -hash-body, so a previous run's output can be used as the baseline. A
+// leading -timestamps time is skipped.
+func loadBaselineHashes(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open baseline hashes: %v", err)
+	}
+	defer file.Close()
+
+	hashes := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 {
+			if _, err := time.Parse(time.RFC3339, fields[0]); err == nil {
+				fields = fields[1:]
+			}
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		hashes[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read baseline hashes: %v", err)
+	}
+	return hashes, nil
+}
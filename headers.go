@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// browserHeaders is the -browser-headers preset, modeled on a desktop Chrome
+// navigation. Accept-Encoding leaves out br, which the standard library
+// cannot decode.
+var browserHeaders = [][2]string{
+	{"User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"},
+	{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"},
+	{"Accept-Language", "en-US,en;q=0.9"},
+	{"Accept-Encoding", "gzip, deflate"},
+	{"Upgrade-Insecure-Requests", "1"},
+	{"Sec-Fetch-Dest", "document"},
+	{"Sec-Fetch-Mode", "navigate"},
+	{"Sec-Fetch-Site", "none"},
+	{"Sec-Fetch-User", "?1"},
+}
+
+// buildRequestHeaders returns the headers sent with every probe: the browser
+// preset when enabled, overridden by any non-empty individual value.
+func buildRequestHeaders(browser bool, userAgent, accept, acceptLanguage, acceptEncoding string) http.Header {
+	header := http.Header{}
+	if browser {
+		for _, h := range browserHeaders {
+			header.Set(h[0], h[1])
+		}
+	}
+	for name, value := range map[string]string{
+		"User-Agent":      userAgent,
+		"Accept":          accept,
+		"Accept-Language": acceptLanguage,
+		"Accept-Encoding": acceptEncoding,
+	} {
+		if value != "" {
+			header.Set(name, value)
+		}
+	}
+	return header
+}
+
+// setRequestHeaders adds the configured request headers and credentials to req.
+func setRequestHeaders(req *http.Request) {
+	for name, values := range requestHeaders {
+		req.Header[name] = values
+	}
+	switch {
+	case basicAuth != "":
+		user, pass, _ := strings.Cut(basicAuth, ":")
+		req.SetBasicAuth(user, pass)
+	case bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+}
+
+// loadCredentials picks the -basic-auth and -bearer credentials. Values given
+// on the command line show up in process listings, so either flag may
+// instead name a variable of the environment or .env as env:NAME, and the
+// token may come from -bearer-file. Variables are only read when named:
+// credentials are sent to every scanned host, so a token merely left in the
+// shell must not go out with them.
+func loadCredentials(basic, bearer, bearerFile string) (string, string, error) {
+	basic, err := credentialFromEnv(basic)
+	if err != nil {
+		return "", "", err
+	}
+	bearer, err = credentialFromEnv(bearer)
+	if err != nil {
+		return "", "", err
+	}
+	if bearer == "" && bearerFile != "" {
+		data, err := os.ReadFile(bearerFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read bearer token: %v", err)
+		}
+		bearer = strings.TrimSpace(string(data))
+	}
+	if basic != "" && !strings.Contains(basic, ":") {
+		return "", "", fmt.Errorf("basic auth must be user:pass")
+	}
+	if basic != "" && bearer != "" {
+		return "", "", fmt.Errorf("basic auth and a bearer token cannot be combined")
+	}
+	return basic, bearer, nil
+}
+
+// credentialFromEnv resolves an env:NAME credential to the variable's value.
+// Other values are returned as given.
+func credentialFromEnv(value string) (string, error) {
+	name, ok := strings.CutPrefix(value, "env:")
+	if !ok {
+		return value, nil
+	}
+	value = os.Getenv(name)
+	if value == "" {
+		return "", fmt.Errorf("credential variable %s is not set", name)
+	}
+	return value, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+// useCredentials loads the credentials for the given flags into the globals
+// setRequestHeaders reads, until the test ends.
+func useCredentials(t *testing.T, basic, bearer string) error {
+	oldBasic, oldBearer := basicAuth, bearerToken
+	t.Cleanup(func() { basicAuth, bearerToken = oldBasic, oldBearer })
+	var err error
+	basicAuth, bearerToken, err = loadCredentials(basic, bearer, "")
+	return err
+}
+
+func authorization(t *testing.T) string {
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setRequestHeaders(req)
+	return req.Header.Get("Authorization")
+}
+
+func TestCredentialsOnlyFromNamedVariables(t *testing.T) {
+	t.Setenv("BASIC_AUTH", "user:pass")
+	t.Setenv("BEARER_TOKEN", "leaked")
+	if err := useCredentials(t, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := authorization(t); got != "" {
+		t.Errorf("Authorization %q sent with no credential flags; want none", got)
+	}
+}
+
+func TestCredentialsFromEnv(t *testing.T) {
+	t.Setenv("SCAN_TOKEN", "secret")
+	if err := useCredentials(t, "", "env:SCAN_TOKEN"); err != nil {
+		t.Fatal(err)
+	}
+	if got := authorization(t); got != "Bearer secret" {
+		t.Errorf("got Authorization %q; want Bearer secret", got)
+	}
+
+	t.Setenv("SCAN_BASIC", "user:pass")
+	if err := useCredentials(t, "env:SCAN_BASIC", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := authorization(t); got != "Basic dXNlcjpwYXNz" {
+		t.Errorf("got Authorization %q; want Basic user:pass", got)
+	}
+
+	if err := useCredentials(t, "", "env:SCAN_UNSET"); err == nil {
+		t.Error("env: naming an unset variable succeeded; want an error")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+)
+
+// headerWriter writes the response headers of survivors to the
+// -headers-file side file under -headers-out with plain output, one JSON
+// object per line. Only the result writer goroutine uses it.
+type headerWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+	count  int
+}
+
+// headerRecord is a line of the -headers-file. Multi-value headers keep
+// every value, in order.
+type headerRecord struct {
+	Domain  string              `json:"domain"`
+	URL     string              `json:"url,omitempty"`
+	Headers map[string][]string `json:"headers"`
+}
+
+func newHeaderWriter(path string, appendMode bool) (*headerWriter, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &headerWriter{file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+// write records the headers of r, if it has any.
+func (w *headerWriter) write(r Result) error {
+	if r.Headers == nil {
+		return nil
+	}
+	data, err := json.Marshal(headerRecord{Domain: r.Domain, URL: r.URL, Headers: r.Headers})
+	if err != nil {
+		return err
+	}
+	w.count++
+	_, err = w.writer.Write(append(data, '\n'))
+	return err
+}
+
+func (w *headerWriter) Close() error {
+	if err := w.writer.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
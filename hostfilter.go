@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+var (
+	// errInvalidHost is returned for input lines whose host is not a valid
+	// host name or IP, such as email addresses or junk.
+	errInvalidHost = errors.New("invalid host")
+	// errExcludedHost is returned for hosts dropped by -include-regex or
+	// -exclude-regex.
+	errExcludedHost = errors.New("excluded host")
+)
+
+// checkHost validates the name t is reported under and applies the
+// -include-regex and -exclude-regex filters to it.
+func checkHost(t target) error {
+	name := t.host
+	if t.hostHeader != "" {
+		name = t.hostHeader
+	}
+	if host, _, err := net.SplitHostPort(name); err == nil {
+		name = host
+	}
+	if checkHostNames && !validHost(t.host) {
+		return errInvalidHost
+	}
+	if includeHosts != nil && !includeHosts.MatchString(name) {
+		return errExcludedHost
+	}
+	if excludeHosts != nil && excludeHosts.MatchString(name) {
+		return errExcludedHost
+	}
+	return nil
+}
+
+// validHost reports whether host, with an optional port, is an IP address
+// or a host name made of letters, digits, hyphens and underscores in labels
+// of up to 63 characters.
+func validHost(host string) bool {
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if port == "" || strings.Trim(port, "0123456789") != "" {
+			return false
+		}
+		host = h
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return true
+	}
+	host = strings.TrimSuffix(host, ".")
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compileHostRegex compiles a -include-regex or -exclude-regex pattern,
+// returning nil for an empty one.
+func compileHostRegex(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
@@ -0,0 +1,48 @@
+package main
+
+import "sync"
+
+// hostLimiter caps concurrent probes per registrable domain, so subdomains
+// served by the same infrastructure share one limit. Semaphores are
+// reference-counted and removed once idle, keeping the map as small as the
+// set of hosts currently being probed.
+type hostLimiter struct {
+	limit int
+
+	mu   sync.Mutex
+	sems map[string]*hostSem
+}
+
+type hostSem struct {
+	slots chan struct{}
+	refs  int
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, sems: make(map[string]*hostSem)}
+}
+
+// acquire blocks until a slot for host is free and returns its release func.
+func (l *hostLimiter) acquire(host string) (release func()) {
+	key := registrableDomain(host)
+
+	l.mu.Lock()
+	sem, ok := l.sems[key]
+	if !ok {
+		sem = &hostSem{slots: make(chan struct{}, l.limit)}
+		l.sems[key] = sem
+	}
+	sem.refs++
+	l.mu.Unlock()
+
+	sem.slots <- struct{}{}
+	return func() {
+		<-sem.slots
+		l.mu.Lock()
+		sem.refs--
+		if sem.refs == 0 {
+			delete(l.sems, key)
+		}
+		l.mu.Unlock()
+	}
+}
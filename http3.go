@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// http3Mode is how -http3 probes hosts over QUIC: "" when off, "also" to add
+// an h3 endpoint after http and https, or "only" to probe nothing else.
+var http3Mode string
+
+var (
+	http3Clients   = map[string]*http.Client{}
+	http3ClientsMu sync.Mutex
+)
+
+// getHTTP3Client returns the HTTP/3 client for the TLS server name
+// serverName ("" for the URL host). It follows the redirect policy and
+// timeout of httpClient.
+func getHTTP3Client(serverName string) *http.Client {
+	http3ClientsMu.Lock()
+	defer http3ClientsMu.Unlock()
+
+	if client, ok := http3Clients[serverName]; ok {
+		return client
+	}
+	client := *httpClient
+	client.Transport = newHTTP3Transport(serverName)
+	http3Clients[serverName] = &client
+	return &client
+}
+
+// altSvcH3 returns the host:port that the Alt-Svc header of resp advertises
+// HTTP/3 on, taking the host of the request when the entry names only a
+// port, or "" when it advertises none.
+func altSvcH3(resp *http.Response) string {
+	for _, s := range parseAltSvc(resp.Header.Values("Alt-Svc")) {
+		if s.protocol != "h3" {
+			continue
+		}
+		host := s.host
+		if host == "" {
+			host = resp.Request.URL.Hostname()
+		}
+		return net.JoinHostPort(host, s.port)
+	}
+	return ""
+}
+
+// confirmHTTP3 requests the URL of resp over HTTP/3 at authority, as
+// advertised by its Alt-Svc header, with the same headers as the probe. Any
+// answer confirms that the host serves HTTP/3.
+func confirmHTTP3(client *http.Client, t target, resp *http.Response, authority string) bool {
+	u := *resp.Request.URL
+	u.Scheme, u.Host = "https", authority
+	req, err := http.NewRequestWithContext(scanCtx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false
+	}
+	setRequestHeaders(req)
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	req.Host = resp.Request.Host
+	h3Resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	h3Resp.Body.Close()
+	return true
+}
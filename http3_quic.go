@@ -0,0 +1,21 @@
+//go:build http3
+
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// http3Supported is set in builds with the http3 tag, which link quic-go.
+const http3Supported = true
+
+// newHTTP3Transport returns a round tripper that speaks HTTP/3 over QUIC,
+// verifying certificates like the TCP transport.
+func newHTTP3Transport(serverName string) http.RoundTripper {
+	return &http3.Transport{
+		TLSClientConfig: &tls.Config{ServerName: serverName, InsecureSkipVerify: insecureTLS},
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !http3
+
+package main
+
+import "net/http"
+
+// http3Supported is unset in default builds, which leave out quic-go; build
+// with -tags http3 for -http3.
+const http3Supported = false
+
+func newHTTP3Transport(serverName string) http.RoundTripper {
+	panic("HTTP/3 support requires building with -tags http3")
+}
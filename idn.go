@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// toASCIIHost converts an internationalized host name, optionally with a
+// port, to its punycode form, e.g. bücher.de to xn--bcher-kva.de. Hosts
+// that are already ASCII are returned unchanged, so IPs and names the IDNA
+// rules would reject, such as ones with underscores, keep working.
+func toASCIIHost(host string) (string, error) {
+	if isASCII(host) {
+		return host, nil
+	}
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = host, ""
+	}
+	ascii, err := idna.Lookup.ToASCII(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid internationalized domain name %q: %v", name, err)
+	}
+	if port != "" {
+		return net.JoinHostPort(ascii, port), nil
+	}
+	return ascii, nil
+}
+
+// asciiTarget converts the host, SNI and Host header of t to punycode.
+// When a name changed, its ASCII form is kept in t.asciiName for the output.
+func asciiTarget(t target) (target, error) {
+	for _, name := range []*string{&t.host, &t.sni, &t.hostHeader} {
+		ascii, err := toASCIIHost(*name)
+		if err != nil {
+			return target{}, err
+		}
+		if ascii != *name {
+			*name = ascii
+			if t.asciiName == "" || name == &t.hostHeader {
+				t.asciiName = ascii
+			}
+		}
+	}
+	return t, nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// jsonTarget is one line of -input-format jsonl. Fields other than host are
+// optional and override the global flags for that target only.
+type jsonTarget struct {
+	Host         string            `json:"host"`
+	Scheme       string            `json:"scheme"`
+	Port         int               `json:"port"`
+	SNI          string            `json:"sni"`
+	Headers      map[string]string `json:"headers"`
+	ExpectStatus int               `json:"expect_status"`
+}
+
+// parseJSONTarget parses a line of -input-format jsonl, e.g.
+//
+//	{"host":"example.com","scheme":"https","port":8443,"expect_status":401}
+func parseJSONTarget(line string) (target, error) {
+	var jt jsonTarget
+	if err := json.Unmarshal([]byte(line), &jt); err != nil {
+		return target{}, err
+	}
+	if jt.Host == "" {
+		return target{}, fmt.Errorf("missing host")
+	}
+	scheme := strings.ToLower(jt.Scheme)
+	if scheme != "" && scheme != "http" && scheme != "https" {
+		return target{}, fmt.Errorf("unsupported scheme %q", jt.Scheme)
+	}
+	if jt.Port < 0 || jt.Port > 65535 {
+		return target{}, fmt.Errorf("invalid port %d", jt.Port)
+	}
+
+	t := target{
+		raw:          jt.Host,
+		host:         jt.Host,
+		sni:          jt.SNI,
+		scheme:       scheme,
+		headers:      jt.Headers,
+		expectStatus: jt.ExpectStatus,
+	}
+	if jt.Port != 0 {
+		t.port = strconv.Itoa(jt.Port)
+	}
+	for name, value := range jt.Headers {
+		if strings.EqualFold(name, "Host") {
+			t.hostHeader = value
+		}
+	}
+	return t, nil
+}
+
+// errHeaderLine is returned for the optional header row of -input-format csv.
+var errHeaderLine = errors.New("header line")
+
+// parseCSVTarget parses a line of -input-format csv, "domain,expected_status",
+// e.g. "example.com,301". An empty status falls back to -status / -alive.
+func parseCSVTarget(line string) (target, error) {
+	host, status, _ := strings.Cut(line, ",")
+	host, status = strings.TrimSpace(host), strings.TrimSpace(status)
+	if strings.EqualFold(host, "domain") && strings.EqualFold(status, "expected_status") {
+		return target{}, errHeaderLine
+	}
+	if host == "" {
+		return target{}, fmt.Errorf("missing domain")
+	}
+	t := target{raw: host, host: host}
+	if status != "" {
+		code, err := strconv.Atoi(status)
+		if err != nil || code < 100 || code > 599 {
+			return target{}, fmt.Errorf("invalid expected status %q", status)
+		}
+		t.expectStatus = code
+	}
+	return t, nil
+}
+
+// lineParser returns the input line parser for -input-format. Unicode host
+// names in any format are converted to punycode before they are requested,
+// trailing dots are handled as -normalize-trailing-dot says, and hosts that
+// are invalid or filtered out fail with errInvalidHost or errExcludedHost.
+func lineParser(format string) (func(string) (target, error), error) {
+	var parse func(string) (target, error)
+	switch format {
+	case "text":
+		parse = func(line string) (target, error) { return parseTarget(line), nil }
+	case "jsonl":
+		parse = parseJSONTarget
+	case "csv":
+		parse = parseCSVTarget
+	default:
+		return nil, fmt.Errorf("unknown input format %q (expected text, jsonl or csv)", format)
+	}
+	return func(line string) (target, error) {
+		t, err := parse(line)
+		if err != nil {
+			return target{}, err
+		}
+		if t, err = asciiTarget(t); err != nil {
+			return target{}, err
+		}
+		t = normalizeTrailingDot(t)
+		if err := checkHost(t); err != nil {
+			return target{}, err
+		}
+		return t, nil
+	}, nil
+}
+
+// expandInputFiles resolves the -l values into input file paths. Each value
+// may be a comma-separated list, and entries with glob characters are
+// expanded in sorted order. Files named more than once are read once.
+func expandInputFiles(values []string) ([]string, error) {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			matches := []string{entry}
+			if strings.ContainsAny(entry, "*?[") {
+				var err error
+				matches, err = filepath.Glob(entry)
+				if err != nil {
+					return nil, fmt.Errorf("invalid input pattern %q: %v", entry, err)
+				}
+				if len(matches) == 0 {
+					return nil, fmt.Errorf("no input files match %q", entry)
+				}
+			}
+			for _, path := range matches {
+				if !seen[path] {
+					seen[path] = true
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no input files given")
+	}
+	return paths, nil
+}
+
+// maxLineBytes is the longest input line that is scanned. Longer lines are
+// skipped, since no host name comes close.
+const maxLineBytes = 1 << 20
+
+// lineReader reads input lines like bufio.Scanner, but skips lines longer
+// than maxLineBytes instead of stopping with bufio.ErrTooLong.
+type lineReader struct {
+	r       *bufio.Reader
+	line    string
+	lineNo  int
+	err     error
+	skipped int
+	// onSkip, when set, is called with the number of each skipped line.
+	onSkip func(lineNo int)
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(r)}
+}
+
+// Scan advances to the next line that fits, reporting false at the end of
+// the input or on a read error.
+func (l *lineReader) Scan() bool {
+	for {
+		var line []byte
+		tooLong := false
+		for {
+			chunk, isPrefix, err := l.r.ReadLine()
+			if err != nil {
+				if err != io.EOF {
+					l.err = err
+				}
+				return false
+			}
+			if !tooLong && len(line)+len(chunk) > maxLineBytes {
+				tooLong = true
+				line = nil
+			}
+			if !tooLong {
+				line = append(line, chunk...)
+			}
+			if !isPrefix {
+				break
+			}
+		}
+		l.lineNo++
+		if tooLong {
+			l.skipped++
+			if l.onSkip != nil {
+				l.onSkip(l.lineNo)
+			}
+			continue
+		}
+		l.line = string(line)
+		return true
+	}
+}
+
+// Text returns the current line, without its line ending.
+func (l *lineReader) Text() string {
+	return l.line
+}
+
+func (l *lineReader) Err() error {
+	return l.err
+}
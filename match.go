@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A match expression combines response checks with boolean logic, e.g.
+//
+//	status=200 && body~"Welcome" || status=403
+//
+// Terms compare a field with a value:
+//
+//	status  =, !=, <, <=, >, >=  against a code, or = / != against a range like 200-299
+//	body    ~ (contains), !~ (does not contain)
+//	header  ~, !~ against "Name: value" lines, case-insensitively
+//	san     ~, !~ against the DNS names of the TLS certificate, case-insensitively
+//	length  =, !=, <, <=, >, >=  against the body length in bytes
+//	alive   on its own, true for any response
+//
+// Values may be bare words or double-quoted strings. Precedence, from
+// highest to lowest, is: parentheses, NOT (!), AND (&&), OR (||); the words
+// NOT, AND and OR may be used instead of the symbols.
+
+// matchInput is what a match expression is evaluated against.
+type matchInput struct {
+	resp *http.Response
+	body []byte
+}
+
+// matchNode is a node of a parsed match expression.
+type matchNode interface {
+	eval(in *matchInput) bool
+	needsBody() bool
+	// explain records the outcome of every term under the node in v.
+	explain(in *matchInput, v *verdict)
+}
+
+type andNode struct{ left, right matchNode }
+type orNode struct{ left, right matchNode }
+type notNode struct{ operand matchNode }
+type aliveNode struct{}
+
+type termNode struct {
+	field string
+	op    string
+	value string
+	// Parsed numeric bounds for status and length comparisons.
+	low, high int
+}
+
+func (n andNode) eval(in *matchInput) bool { return n.left.eval(in) && n.right.eval(in) }
+func (n orNode) eval(in *matchInput) bool  { return n.left.eval(in) || n.right.eval(in) }
+func (n notNode) eval(in *matchInput) bool { return !n.operand.eval(in) }
+func (aliveNode) eval(*matchInput) bool    { return true }
+
+func (n andNode) needsBody() bool { return n.left.needsBody() || n.right.needsBody() }
+func (n orNode) needsBody() bool  { return n.left.needsBody() || n.right.needsBody() }
+func (n notNode) needsBody() bool { return n.operand.needsBody() }
+func (aliveNode) needsBody() bool { return false }
+func (n termNode) needsBody() bool {
+	return n.field == "body" || n.field == "length"
+}
+
+func (n andNode) explain(in *matchInput, v *verdict) { n.left.explain(in, v); n.right.explain(in, v) }
+func (n orNode) explain(in *matchInput, v *verdict)  { n.left.explain(in, v); n.right.explain(in, v) }
+func (n notNode) explain(in *matchInput, v *verdict) { n.operand.explain(in, v) }
+func (aliveNode) explain(in *matchInput, v *verdict) { v.add("alive", true, "") }
+func (n termNode) explain(in *matchInput, v *verdict) {
+	detail := ""
+	switch n.field {
+	case "status":
+		detail = fmt.Sprintf("got %d", in.resp.StatusCode)
+	case "length":
+		detail = fmt.Sprintf("got %d", max(len(in.body), int(in.resp.ContentLength)))
+	}
+	v.add(fmt.Sprintf("%s%s%q", n.field, n.op, n.value), n.eval(in), detail)
+}
+
+func (n termNode) eval(in *matchInput) bool {
+	switch n.field {
+	case "status":
+		return compareInt(in.resp.StatusCode, n.op, n.low, n.high)
+	case "length":
+		length := len(in.body)
+		if in.resp.ContentLength > int64(length) {
+			length = int(in.resp.ContentLength)
+		}
+		return compareInt(length, n.op, n.low, n.high)
+	case "body":
+		found := bytes.Contains(in.body, []byte(n.value))
+		return found == (n.op == "~")
+	case "header":
+		found := headerContains(in.resp.Header, n.value)
+		return found == (n.op == "~")
+	case "san":
+		found := matchingSAN(in.resp.TLS, []string{n.value}) != ""
+		return found == (n.op == "~")
+	}
+	return false
+}
+
+// compareInt applies op to v. For = and != the value may be a low-high range.
+func compareInt(v int, op string, low, high int) bool {
+	switch op {
+	case "=":
+		return v >= low && v <= high
+	case "!=":
+		return v < low || v > high
+	case "<":
+		return v < low
+	case "<=":
+		return v <= low
+	case ">":
+		return v > low
+	case ">=":
+		return v >= low
+	}
+	return false
+}
+
+// headerContains reports whether any "Name: value" header line contains
+// substr, ignoring case.
+func headerContains(header http.Header, substr string) bool {
+	substr = strings.ToLower(substr)
+	for name, values := range header {
+		for _, value := range values {
+			if strings.Contains(strings.ToLower(name+": "+value), substr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchingSAN returns the first DNS name of the leaf certificate in state
+// that contains any of keywords, ignoring case, or "" when none does.
+func matchingSAN(state *tls.ConnectionState, keywords []string) string {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	for _, name := range state.PeerCertificates[0].DNSNames {
+		lower := strings.ToLower(name)
+		for _, keyword := range keywords {
+			if strings.Contains(lower, strings.ToLower(keyword)) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// parseMatchExpr parses a -match-expr expression.
+func parseMatchExpr(expr string) (matchNode, error) {
+	tokens, err := tokenizeMatchExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &matchParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in match expression", p.tokens[p.pos].text)
+	}
+	return node, nil
+}
+
+type matchToken struct {
+	text   string
+	quoted bool
+}
+
+// tokenizeMatchExpr splits an expression into words, quoted strings,
+// operators and parentheses.
+func tokenizeMatchExpr(expr string) ([]matchToken, error) {
+	var tokens []matchToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			var b strings.Builder
+			i++
+			for i < len(expr) && expr[i] != '"' {
+				if expr[i] == '\\' && i+1 < len(expr) {
+					i++
+				}
+				b.WriteByte(expr[i])
+				i++
+			}
+			if i >= len(expr) {
+				return nil, fmt.Errorf("unterminated string in match expression")
+			}
+			i++
+			tokens = append(tokens, matchToken{text: b.String(), quoted: true})
+		case strings.HasPrefix(expr[i:], "&&"), strings.HasPrefix(expr[i:], "||"),
+			strings.HasPrefix(expr[i:], "!="), strings.HasPrefix(expr[i:], "!~"),
+			strings.HasPrefix(expr[i:], "<="), strings.HasPrefix(expr[i:], ">="):
+			tokens = append(tokens, matchToken{text: expr[i : i+2]})
+			i += 2
+		case strings.ContainsRune("()!=~<>", rune(c)):
+			tokens = append(tokens, matchToken{text: expr[i : i+1]})
+			i++
+		default:
+			start := i
+			for i < len(expr) && !strings.ContainsRune(" \t\"()!=~<>&|", rune(expr[i])) {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("unexpected %q in match expression", string(c))
+			}
+			tokens = append(tokens, matchToken{text: expr[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+// matchParser is a recursive-descent parser over match expression tokens.
+type matchParser struct {
+	tokens []matchToken
+	pos    int
+}
+
+func (p *matchParser) peek() (matchToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return matchToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// accept consumes the next token if it is one of the given unquoted keywords.
+func (p *matchParser) accept(keywords ...string) bool {
+	tok, ok := p.peek()
+	if !ok || tok.quoted {
+		return false
+	}
+	for _, kw := range keywords {
+		if strings.EqualFold(tok.text, kw) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *matchParser) parseOr() (matchNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||", "or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *matchParser) parseAnd() (matchNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&", "and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *matchParser) parseUnary() (matchNode, error) {
+	if p.accept("!", "not") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	if p.accept("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing ) in match expression")
+		}
+		return node, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *matchParser) parseTerm() (matchNode, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("match expression ends unexpectedly")
+	}
+	p.pos++
+	field := strings.ToLower(tok.text)
+	if field == "alive" {
+		return aliveNode{}, nil
+	}
+
+	opTok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("missing operator after %q in match expression", tok.text)
+	}
+	p.pos++
+	valueTok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("missing value after %s%s in match expression", tok.text, opTok.text)
+	}
+	p.pos++
+
+	term := termNode{field: field, op: opTok.text, value: valueTok.text}
+	switch field {
+	case "status", "length":
+		switch term.op {
+		case "=", "!=", "<", "<=", ">", ">=":
+		default:
+			return nil, fmt.Errorf("%s does not support operator %q", field, term.op)
+		}
+		if err := term.parseBounds(); err != nil {
+			return nil, err
+		}
+	case "body", "header", "san":
+		if term.op != "~" && term.op != "!~" {
+			return nil, fmt.Errorf("%s only supports ~ and !~, not %q", field, term.op)
+		}
+	default:
+		return nil, fmt.Errorf("unknown match field %q (expected status, body, header, san, length or alive)", tok.text)
+	}
+	return term, nil
+}
+
+// parseBounds parses an integer value, or a low-high range for = and !=.
+func (n *termNode) parseBounds() error {
+	lowStr, highStr, isRange := strings.Cut(n.value, "-")
+	if isRange && n.op != "=" && n.op != "!=" {
+		return fmt.Errorf("ranges like %q only work with = and !=", n.value)
+	}
+	low, err := strconv.Atoi(strings.TrimFunc(lowStr, unicode.IsSpace))
+	if err != nil {
+		return fmt.Errorf("invalid number %q in match expression", n.value)
+	}
+	high := low
+	if isRange {
+		high, err = strconv.Atoi(strings.TrimFunc(highStr, unicode.IsSpace))
+		if err != nil || high < low {
+			return fmt.Errorf("invalid range %q in match expression", n.value)
+		}
+	}
+	n.low, n.high = low, high
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// byteBudget bounds the bytes of response bodies held by all workers at
+// once under -mem-budget. Workers reserve a body's expected size before
+// reading it and wait while the budget is used up, so memory stays bounded
+// however many workers run.
+type byteBudget struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	total int64
+	used  int64
+}
+
+func newByteBudget(total int64) *byteBudget {
+	b := &byteBudget{total: total}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire reserves n bytes, waiting until they are free or the scan stops.
+func (b *byteBudget) acquire(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.used+n > b.total && b.used > 0 && scanCtx.Err() == nil {
+		b.cond.Wait()
+	}
+	b.used += n
+}
+
+// take reserves n bytes without waiting, even past the budget.
+func (b *byteBudget) take(n int64) {
+	b.mu.Lock()
+	b.used += n
+	b.mu.Unlock()
+}
+
+// release returns n reserved bytes to the budget.
+func (b *byteBudget) release(n int64) {
+	if n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used -= n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// wake releases waiting workers once the scan has been stopped.
+func (b *byteBudget) wake() {
+	b.cond.Broadcast()
+}
+
+// expectedBodySize is how much of resp's body readBody may hold: its
+// Content-Length when known and not compressed, otherwise maxBodyBytes.
+func expectedBodySize(resp *http.Response) int64 {
+	if resp.ContentLength >= 0 && strings.TrimSpace(resp.Header.Get("Content-Encoding")) == "" {
+		return min(resp.ContentLength, maxBodyBytes)
+	}
+	return maxBodyBytes
+}
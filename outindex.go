@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// outputIndex is the -index companion of the output file: a "record offset"
+// line for every -index-every'th survivor, giving the byte offset its line
+// starts at, so consumers can seek into very large outputs. Entries are only
+// written once the output has been flushed past the end of their record, so
+// the index never points at bytes that are not in the output file yet.
+type outputIndex struct {
+	file    *os.File
+	writer  *bufio.Writer
+	every   int64
+	records int64 // Records in the output so far.
+	pending []indexEntry
+}
+
+// indexEntry locates a record in the output: its number, counting from 0,
+// and the offsets where its line starts and ends.
+type indexEntry struct {
+	record, start, end int64
+}
+
+// newOutputIndex creates the index at path for an output that already holds
+// records lines, adding to an existing index under appendMode.
+func newOutputIndex(path string, every int, appendMode bool, records int64) (*outputIndex, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &outputIndex{file: file, writer: bufio.NewWriter(file), every: int64(every), records: records}, nil
+}
+
+// add records that the next survivor's line spans the offsets start to end.
+func (x *outputIndex) add(start, end int64) {
+	if x.records%x.every == 0 {
+		x.pending = append(x.pending, indexEntry{record: x.records, start: start, end: end})
+	}
+	x.records++
+}
+
+// sync writes the pending entries whose record lies within the first
+// flushed bytes of the output.
+func (x *outputIndex) sync(flushed int64) error {
+	n := 0
+	for n < len(x.pending) && x.pending[n].end <= flushed {
+		if _, err := fmt.Fprintf(x.writer, "%d %d\n", x.pending[n].record, x.pending[n].start); err != nil {
+			return err
+		}
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	x.pending = x.pending[n:]
+	return x.writer.Flush()
+}
+
+func (x *outputIndex) Close() error {
+	if err := x.writer.Flush(); err != nil {
+		x.file.Close()
+		return err
+	}
+	return x.file.Close()
+}
+
+// countOutputLines returns the number of lines in the file at path, or 0 when it
+// does not exist, to continue record numbers when appending to an output.
+func countOutputLines(path string) (int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	var lines int64
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := file.Read(buf)
+		lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
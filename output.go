@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result is the outcome for a single target, sent from fetchURL to the result writer.
+type Result struct {
+	Domain         string  `json:"domain"`
+	URL            string  `json:"url,omitempty"`
+	StatusCode     int     `json:"status,omitempty"`
+	ResponseTimeMs float64 `json:"response_time_ms,omitempty"`
+	// Title is the page <title>, kept for -sink sqlite.
+	Title string `json:"title,omitempty"`
+	// Score is the -score-weights score of the response under -min-score.
+	Score int `json:"score,omitempty"`
+	// ASCIIDomain is the punycode form of a Unicode Domain, as requested.
+	ASCIIDomain string `json:"ascii_domain,omitempty"`
+	// Proto is the HTTP version the response was served over under -http3,
+	// e.g. HTTP/1.1 or HTTP/3.0.
+	Proto string `json:"proto,omitempty"`
+	// HTTP3 is where a survivor reached over TCP answered over HTTP/3 as
+	// advertised by its Alt-Svc header, under -http3.
+	HTTP3 string `json:"http3,omitempty"`
+	// AltSvc lists the alternative services the response advertised under
+	// -alt-svc, as protocol=host:port with an empty host for the same host.
+	AltSvc []string `json:"alt_svc,omitempty"`
+	// Protocols lists every protocol on which the domain matched.
+	Protocols []string `json:"protocols,omitempty"`
+	// Technologies lists what -fingerprint detected, with versions where known.
+	Technologies []string `json:"technologies,omitempty"`
+	// Headers holds every response header of the survivor under -headers-out.
+	Headers map[string][]string `json:"headers,omitempty"`
+	// SAN is the certificate DNS name that matched -match-san.
+	SAN string `json:"san,omitempty"`
+	// Variants lists the hosts that matched under -expand-www.
+	Variants []string `json:"variants,omitempty"`
+	// RedirectedOffsite is set when the final URL after redirects is on another
+	// registrable domain than the requested host, which is then in RedirectTo.
+	RedirectedOffsite bool   `json:"redirected_offsite,omitempty"`
+	RedirectTo        string `json:"redirect_to,omitempty"`
+	// BodyHash is the SHA-256 of the response body under -hash-body.
+	BodyHash string `json:"body_sha256,omitempty"`
+	// CaseVariant is the randomly cased path that answered differently under
+	// -case-probe, with its status, or "body" when only the body differed.
+	CaseVariant string `json:"case_variant,omitempty"`
+	// BodyFile is where the matched body was saved under -save-bodies.
+	BodyFile string `json:"body_file,omitempty"`
+	// HashChanged is set when BodyHash differs from the -baseline-hashes entry.
+	HashChanged bool `json:"hash_changed,omitempty"`
+	// FaviconHash is the Shodan-style favicon hash under -favicon-hash.
+	FaviconHash *int32 `json:"favicon_hash,omitempty"`
+	// Registrar and Expires describe the registrable domain under -whois.
+	Registrar string `json:"registrar,omitempty"`
+	Expires   string `json:"expires,omitempty"`
+	// FoundAt is when the result writer received the survivor, under -timestamps.
+	FoundAt string `json:"found_at,omitempty"`
+	// ExtendedTimeout is set when the domain only answered the
+	// -probe-timeout-retry-once retry with the longer timeout.
+	ExtendedTimeout bool `json:"extended_timeout,omitempty"`
+	// Slow is set when the response took longer than -slow-threshold.
+	Slow bool `json:"slow,omitempty"`
+	// Reason explains why a domain failed the match criteria under -invert.
+	Reason string `json:"reason,omitempty"`
+	// Error is the category of the last fetch error when no protocol responded.
+	Error string `json:"error,omitempty"`
+
+	emit bool // Written to the main output.
+	dead bool // No protocol responded; counted and written to -o-dead.
+
+	host       string        // Name probed, whose registrable domain goes to -group-apex.
+	matches    []Result      // Each matching endpoint under -all-protocols.
+	retryAfter time.Duration // Delay requested by a rate-limited response, or 0.
+	page       pageState     // Compared with the previous run under -only-changed.
+	timedOut   bool          // Some attempt timed out, for -probe-timeout-retry-once.
+	seq        int           // Input position of the target, for -sorted-output.
+	whois      bool          // Matched, so -whois looks up its registration.
+}
+
+// addMatch keeps the endpoint just filled into r as a match of its own
+// under -all-protocols.
+func (r *Result) addMatch(protocol string) {
+	m := *r
+	m.matches = nil
+	m.Protocols = []string{protocol}
+	r.matches = append(r.matches, m)
+}
+
+// useFirstMatch makes r describe its first match, if any were kept, listing
+// the protocols of all of them.
+func (r *Result) useFirstMatch() {
+	if len(r.matches) == 0 {
+		return
+	}
+	matches := r.matches
+	var protocols []string
+	for _, m := range matches {
+		if !slices.Contains(protocols, m.Protocols[0]) {
+			protocols = append(protocols, m.Protocols[0])
+		}
+	}
+	*r = matches[0]
+	r.matches, r.Protocols = matches, protocols
+}
+
+// records returns the output records for r: one per match kept under
+// -all-protocols, or r itself. Each record keeps its own endpoint details
+// and shares the per-target ones filled in by fetchURL.
+func (r Result) records() []Result {
+	if len(r.matches) < 2 {
+		return []Result{r}
+	}
+	records := make([]Result, len(r.matches))
+	for i, m := range r.matches {
+		m.Variants, m.FaviconHash, m.Registrar, m.Expires = r.Variants, r.FaviconHash, r.Registrar, r.Expires
+		m.host, m.dead, m.Reason, m.whois = r.host, r.dead, r.Reason, r.whois
+		records[i] = m
+	}
+	return records
+}
+
+// applyOutcome fills in r from an outcome cached earlier in the run for a
+// request to host.
+func (r *Result) applyOutcome(url, host string, o cachedOutcome) {
+	r.Error = ""
+	r.URL = url
+	r.StatusCode = o.statusCode
+	r.BodyHash = o.bodyHash
+	r.page = o.page
+	r.Score = o.score
+	r.Headers = o.headers
+	r.AltSvc = o.altSvc
+	r.Title = o.title
+	r.ResponseTimeMs, r.Slow = o.responseTimeMs, o.slow
+	r.Proto, r.HTTP3 = o.proto, o.http3
+	r.Technologies, r.SAN, r.CaseVariant, r.BodyFile = o.technologies, o.san, o.caseVariant, o.bodyFile
+	r.noteRedirect(host, o.finalURL)
+}
+
+// noteRedirect tags r as redirected offsite when finalURL is on another
+// registrable domain than the requested host.
+func (r *Result) noteRedirect(host, finalURL string) {
+	r.RedirectedOffsite, r.RedirectTo = false, ""
+	u, err := url.Parse(finalURL)
+	if err != nil || u.Host == "" || registrableDomain(u.Host) == registrableDomain(host) {
+		return
+	}
+	r.RedirectedOffsite = true
+	r.RedirectTo = finalURL
+}
+
+// jsonFields selects and orders the keys of JSON records, set with -fields.
+// nil writes every field.
+var jsonFields []string
+
+// marshalResult encodes r as a JSON object, keeping only jsonFields when set.
+// Empty fields are left out either way.
+func marshalResult(r Result) ([]byte, error) {
+	data, err := json.Marshal(r)
+	if err != nil || jsonFields == nil {
+		return data, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, name := range jsonFields {
+		value, ok := all[name]
+		if !ok {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// parseFields checks a comma-separated -fields list against the JSON keys
+// of Result.
+func parseFields(list string) ([]string, error) {
+	valid := make(map[string]bool)
+	var names []string
+	t := reflect.TypeOf(Result{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			valid[name] = true
+			names = append(names, name)
+		}
+	}
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !valid[field] {
+			return nil, fmt.Errorf("unknown field %q (valid fields: %s)", field, strings.Join(names, ", "))
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("-fields needs at least one field")
+	}
+	return fields, nil
+}
+
+// formatResult renders a result as an output line, without the trailing newline.
+// Plain lines start with the domain and body hash (if any), after the
+// -timestamps time, followed by any space-separated tags, so -hash-body
+// output can be reused as -baseline-hashes.
+func formatResult(r Result, asJSON bool) (string, error) {
+	if asJSON {
+		data, err := marshalResult(r)
+		return string(data), err
+	}
+	domain := r.Domain
+	if showScheme && len(r.Protocols) > 0 {
+		// The first match, or this record's own under -all-protocols.
+		domain = r.Protocols[0] + "://" + domain
+	}
+	var fields []string
+	if r.FoundAt != "" {
+		fields = append(fields, r.FoundAt)
+	}
+	fields = append(fields, domain)
+	if r.BodyHash != "" {
+		fields = append(fields, r.BodyHash)
+	}
+	if showStatus && r.StatusCode != 0 {
+		fields = append(fields, strconv.Itoa(r.StatusCode))
+	}
+	if r.ASCIIDomain != "" {
+		fields = append(fields, "ascii="+r.ASCIIDomain)
+	}
+	if r.Score != 0 {
+		fields = append(fields, "score="+strconv.Itoa(r.Score))
+	}
+	if allProtocols && r.URL != "" {
+		fields = append(fields, r.URL) // Tells apart the records of one domain.
+	}
+	if r.Reason != "" {
+		fields = append(fields, r.Reason)
+	}
+	if r.Error != "" {
+		fields = append(fields, r.Error)
+	}
+	if r.HashChanged {
+		fields = append(fields, "hash-changed")
+	}
+	if r.FaviconHash != nil {
+		fields = append(fields, fmt.Sprintf("favicon=%d", *r.FaviconHash))
+	}
+	if len(r.Technologies) > 0 {
+		fields = append(fields, "tech="+strings.Join(r.Technologies, ","))
+	}
+	if r.SAN != "" {
+		fields = append(fields, "san="+r.SAN)
+	}
+	if len(r.Variants) > 0 {
+		fields = append(fields, "variants="+strings.Join(r.Variants, ","))
+	}
+	if r.Expires != "" {
+		fields = append(fields, "expires="+r.Expires)
+	}
+	if r.Registrar != "" {
+		fields = append(fields, fmt.Sprintf("registrar=%q", r.Registrar))
+	}
+	if r.Proto != "" {
+		fields = append(fields, "proto="+r.Proto)
+	}
+	if r.HTTP3 != "" {
+		fields = append(fields, "h3="+r.HTTP3)
+	}
+	if len(r.AltSvc) > 0 {
+		fields = append(fields, "alt-svc="+strings.Join(r.AltSvc, ","))
+	}
+	if r.CaseVariant != "" {
+		fields = append(fields, "case="+r.CaseVariant)
+	}
+	if r.RedirectedOffsite {
+		fields = append(fields, "redirect="+r.RedirectTo)
+	}
+	if r.ExtendedTimeout {
+		fields = append(fields, "extended-timeout")
+	}
+	if r.Slow {
+		fields = append(fields, "slow")
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// writeResult formats r and writes it as a line to w, in color unless color
+// is "", flushing immediately when stream is set.
+func writeResult(w *bufio.Writer, r Result, asJSON, stream bool, color string) error {
+	line, err := formatResult(r, asJSON)
+	if err != nil {
+		return err
+	}
+	if _, err := w.WriteString(colorize(color, line) + "\n"); err != nil {
+		return err
+	}
+	if stream {
+		return w.Flush()
+	}
+	return nil
+}
+
+// printDeadCounts summarizes domains that did not respond by error category.
+func printDeadCounts(counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	categories := make([]string, 0, len(counts))
+	for category := range counts {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	fmt.Fprintln(console, "Domains without a response, by error:")
+	for _, category := range categories {
+		fmt.Fprintf(console, "  %-20s %d\n", category, counts[category])
+	}
+}
+
+// seenSet is a concurrency-safe set of domains already written to the output.
+type seenSet struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+func newSeenSet() *seenSet {
+	return &seenSet{seen: make(map[string]struct{})}
+}
+
+// add records key and reports whether it was new.
+func (s *seenSet) add(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.seen[key]; ok {
+		return false
+	}
+	s.seen[key] = struct{}{}
+	return true
+}
+
+// slowestResults keeps the n slowest results seen so far. It is only used by
+// the result writer goroutine and is not safe for concurrent use.
+type slowestResults struct {
+	n       int
+	results []Result
+}
+
+func (s *slowestResults) add(r Result) {
+	if s.n <= 0 || r.ResponseTimeMs <= 0 {
+		return
+	}
+	i := sort.Search(len(s.results), func(i int) bool {
+		return s.results[i].ResponseTimeMs < r.ResponseTimeMs
+	})
+	if i >= s.n {
+		return
+	}
+	s.results = append(s.results, Result{})
+	copy(s.results[i+1:], s.results[i:])
+	s.results[i] = r
+	if len(s.results) > s.n {
+		s.results = s.results[:s.n]
+	}
+}
+
+// print writes the slow-host report, slowest first.
+func (s *slowestResults) print() {
+	if len(s.results) == 0 {
+		return
+	}
+	fmt.Fprintf(console, "Slowest %d hosts:\n", len(s.results))
+	for _, r := range s.results {
+		fmt.Fprintf(console, "  %9.1f ms  %s\n", r.ResponseTimeMs, r.Domain)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import "sync"
+
+// pauseGate holds workers back from starting new targets while paused.
+// Requests already in flight are not interrupted.
+type pauseGate struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	paused bool
+}
+
+func newPauseGate() *pauseGate {
+	g := &pauseGate{}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+// toggle flips the paused state and reports whether the gate is now paused.
+func (g *pauseGate) toggle() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.paused = !g.paused
+	if !g.paused {
+		g.cond.Broadcast()
+	}
+	return g.paused
+}
+
+// wait blocks while the gate is paused, unless the scan has been stopped.
+func (g *pauseGate) wait() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.paused && scanCtx.Err() == nil {
+		g.cond.Wait()
+	}
+}
+
+// wake lets waiting workers recheck whether the scan has been stopped.
+func (g *pauseGate) wake() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cond.Broadcast()
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchPauseSignal toggles g on every SIGUSR1.
+func watchPauseSignal(g *pauseGate) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1)
+	go func() {
+		for range sigs {
+			if g.toggle() {
+				fmt.Fprintln(console, "Scan paused; in-flight requests will finish. Send SIGUSR1 again to resume.")
+			} else {
+				fmt.Fprintln(console, "Scan resumed.")
+			}
+		}
+	}()
+}
@@ -0,0 +1,4 @@
+package main
+
+// watchPauseSignal is a no-op: Windows has no SIGUSR1, so scans cannot be paused.
+func watchPauseSignal(g *pauseGate) {}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProtocolTimeouts(t *testing.T) {
+	timeouts, err := parseProtocolTimeouts("http=3s, https=10s")
+	if err != nil || timeouts["http"] != 3*time.Second || timeouts["https"] != 10*time.Second {
+		t.Fatalf("got %v, %v; want http=3s and https=10s", timeouts, err)
+	}
+	for _, list := range []string{"ftp=3s", "http", "http=soon", "https=0s", "http=-1s"} {
+		if _, err := parseProtocolTimeouts(list); err == nil {
+			t.Errorf("parseProtocolTimeouts(%q) succeeded; want an error", list)
+		}
+	}
+}
+
+// TestProtocolTimeouts checks that each attempt gets its own timeout from
+// -timeout-per-protocol rather than the client's -timeout, and that the
+// attempts do not share one budget.
+func TestProtocolTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	oldClient, oldTimeouts := httpClient, protocolTimeouts
+	defer func() { httpClient, protocolTimeouts = oldClient, oldTimeouts }()
+	httpClient = getHTTPClient(5*time.Second, true)
+	probeHTTPOnce := func() (Result, time.Duration, bool) {
+		var result Result
+		start := time.Now()
+		matched, _ := probeHTTP(target{raw: host, host: host, scheme: "http"}, &result, singleStatus(http.StatusOK), false)
+		return result, time.Since(start), matched
+	}
+
+	protocolTimeouts = map[string]time.Duration{"http": 100 * time.Millisecond}
+	result, elapsed, matched := probeHTTPOnce()
+	if matched || result.Error != "timeout" {
+		t.Errorf("http=100ms: got matched=%v, error %q; want a timeout", matched, result.Error)
+	}
+	if elapsed >= time.Second {
+		t.Errorf("http=100ms: took %v; want the http timeout, not -timeout", elapsed)
+	}
+
+	// The https timeout does not cut the http attempt short.
+	protocolTimeouts = map[string]time.Duration{"http": 2 * time.Second, "https": 100 * time.Millisecond}
+	if result, _, matched := probeHTTPOnce(); !matched {
+		t.Errorf("http=2s,https=100ms: got error %q; want a match over http", result.Error)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAuthProxy starts a stub proxy that answers 407 unless a request carries
+// the Proxy-Authorization value want. It tunnels CONNECT requests and answers
+// plain http requests itself with the URL it was asked for.
+func newAuthProxy(t *testing.T, want string) *httptest.Server {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != want {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		if r.Method != http.MethodConnect {
+			fmt.Fprint(w, "proxied "+r.URL.String())
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+// useProxy makes entry the only configured proxy, with authorization as
+// PROXY_AUTHORIZATION, until the test ends.
+func useProxy(t *testing.T, entry, authorization string) {
+	oldProxies, oldAuthorization, oldInsecure := proxies, proxyAuthorization, insecureTLS
+	t.Cleanup(func() {
+		proxies, proxyAuthorization, insecureTLS = oldProxies, oldAuthorization, oldInsecure
+		proxyIndex = 0
+	})
+	proxies, proxyAuthorization, insecureTLS = []string{entry}, authorization, true
+	proxyIndex = 0
+}
+
+func get(client *http.Client, target string) (string, error) {
+	resp, err := client.Get(target)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return string(body), err
+}
+
+func TestConnectProxyAuthorization(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer target.Close()
+
+	t.Run("PROXY_AUTHORIZATION", func(t *testing.T) {
+		proxy := newAuthProxy(t, "Bearer secret")
+		useProxy(t, proxy.URL, "Bearer secret")
+		body, err := get(getHTTPClient(5*time.Second, true), target.URL)
+		if err != nil || body != "ok" {
+			t.Fatalf("got %q, %v; want ok through the tunnel", body, err)
+		}
+	})
+	t.Run("credentials in the proxy URL", func(t *testing.T) {
+		proxy := newAuthProxy(t, "Basic dXNlcjpwYXNz") // user:pass
+		proxyURL, _ := url.Parse(proxy.URL)
+		proxyURL.User = url.UserPassword("user", "pass")
+		useProxy(t, proxyURL.String(), "Bearer ignored")
+		body, err := get(getHTTPClient(5*time.Second, true), target.URL)
+		if err != nil || body != "ok" {
+			t.Fatalf("got %q, %v; want ok through the tunnel", body, err)
+		}
+	})
+	t.Run("missing authorization", func(t *testing.T) {
+		proxy := newAuthProxy(t, "Bearer secret")
+		useProxy(t, proxy.URL, "")
+		if _, err := get(getHTTPClient(5*time.Second, true), target.URL); err == nil {
+			t.Fatal("CONNECT without Proxy-Authorization succeeded; want it refused")
+		}
+	})
+}
+
+func TestPlainHTTPProxyAuthorization(t *testing.T) {
+	proxy := newAuthProxy(t, "Basic dXNlcjpwYXNz") // user:pass
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyURL.User = url.UserPassword("user", "pass")
+	useProxy(t, proxyURL.String(), "Bearer ignored")
+
+	req, err := http.NewRequest(http.MethodGet, "http://target.invalid/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := getHTTPClient(5*time.Second, true).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(string(body), "proxied http://target.invalid/") {
+		t.Fatalf("got %d %q; want the request forwarded with the proxy's credentials", resp.StatusCode, body)
+	}
+	if len(req.Header) != 0 {
+		t.Errorf("request headers changed to %v; the transport must not modify the request", req.Header)
+	}
+}
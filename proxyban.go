@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// blockSignatures are lowercase body snippets of the challenge and captcha
+// pages served by anti-bot systems in front of the scanned hosts. Generic
+// denials such as "access denied" are left out: S3 and other origins answer
+// that on their own, whatever the proxy.
+var blockSignatures = [][]byte{
+	[]byte("cf-chl"), // Cloudflare challenge.
+	[]byte("attention required! | cloudflare"), // Cloudflare block page.
+	[]byte("captcha-delivery.com"),             // DataDome.
+	[]byte("px-captcha"),                       // PerimeterX.
+	[]byte("unusual traffic"),                  // Google.
+}
+
+// proxyBan is the ban state of one proxy: consecutive block responses seen
+// through it, and until when it is benched.
+type proxyBan struct {
+	strikes      int
+	benchedUntil time.Time
+}
+
+// proxyChoiceKey is the request context key holding a *proxyChoice.
+type proxyChoiceKey struct{}
+
+// proxyChoice records which proxy entry the transport picked for a request.
+type proxyChoice struct {
+	entry string
+}
+
+// withProxyChoice returns ctx carrying choice, filled in by getNextProxyURL.
+func withProxyChoice(ctx context.Context, choice *proxyChoice) context.Context {
+	return context.WithValue(ctx, proxyChoiceKey{}, choice)
+}
+
+// isBlockResponse reports whether resp looks like a ban rather than the
+// host's own answer: a 429, or a body with a challenge page signature. A
+// plain 403 is too common an answer of the hosts themselves to count.
+func isBlockResponse(resp *http.Response, body []byte) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	lower := bytes.ToLower(body)
+	for _, sig := range blockSignatures {
+		if bytes.Contains(lower, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyBenched reports whether entry is benched. Callers hold proxyMu.
+func proxyBenched(entry string, now time.Time) bool {
+	ban, ok := proxyBans[entry]
+	return ok && now.Before(ban.benchedUntil)
+}
+
+// noteProxyResponse counts a block response through entry, benching the
+// proxy for -proxy-cooldown after -proxy-ban-threshold in a row. Any other
+// response resets the count.
+func noteProxyResponse(entry string, blocked bool) {
+	proxyMu.Lock()
+	defer proxyMu.Unlock()
+
+	ban, ok := proxyBans[entry]
+	if !ok {
+		ban = &proxyBan{}
+		proxyBans[entry] = ban
+	}
+	if !blocked {
+		ban.strikes = 0
+		return
+	}
+	ban.strikes++
+	if ban.strikes < proxyBanThreshold {
+		return
+	}
+	ban.strikes = 0
+	ban.benchedUntil = time.Now().Add(proxyCooldown)
+	name := entry
+	if proxyURL, err := parseProxyURL(entry); err == nil {
+		name = proxyURL.Redacted()
+	}
+	fmt.Fprintf(console, "Proxy %s looks banned (%d block responses in a row); benching it for %v\n", name, proxyBanThreshold, proxyCooldown)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// proxyUserTemplate is the -proxy-user-template proxy username, in which
+// {rand} and {user} are filled in for every request; "" when unset.
+var proxyUserTemplate string
+
+// templateProxyUser returns proxyURL with its username built from
+// proxyUserTemplate: {rand} becomes a new random session ID, so providers
+// that pick the exit IP by session rotate it on every request, and {user}
+// the username the proxy was configured with. The password is kept.
+func templateProxyUser(proxyURL *url.URL) *url.URL {
+	user, password := "", ""
+	if proxyURL.User != nil {
+		user = proxyURL.User.Username()
+		password, _ = proxyURL.User.Password()
+	} else {
+		user, password = proxyUsername, proxyPassword
+	}
+	// Session IDs do not follow -seed: repeated runs would otherwise get
+	// the same exit IPs, and the IDs would be predictable.
+	session := make([]byte, 8)
+	rand.Read(session)
+	name := strings.NewReplacer("{rand}", hex.EncodeToString(session), "{user}", user).Replace(proxyUserTemplate)
+
+	templated := *proxyURL
+	if password != "" {
+		templated.User = url.UserPassword(name, password)
+	} else {
+		templated.User = url.User(name)
+	}
+	return &templated
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// rdapBootstrapURL lists the RDAP server responsible for each TLD (RFC 9224).
+const rdapBootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+// rdapMaxBytes caps the RDAP responses read, which are a few KB normally.
+const rdapMaxBytes = 1 << 20
+
+// maxPendingLookups is how many survivors may wait on RDAP lookups at once
+// under -whois before the scan workers wait for room.
+const maxPendingLookups = 1024
+
+// errNotRegistered is returned for domains the RDAP server does not know.
+var errNotRegistered = errors.New("domain not found in RDAP")
+
+// registration is the registrar and expiry date of a registrable domain.
+type registration struct {
+	registrar string
+	expires   time.Time
+}
+
+// registrationEntry is a cached RDAP lookup. ready is closed once the
+// lookup finishes, so survivors sharing a registrable domain wait for one query.
+type registrationEntry struct {
+	ready chan struct{}
+	reg   registration
+	err   error
+}
+
+// rdapClient looks up domain registrations over RDAP, the structured
+// successor of WHOIS. Queries to each server are spaced at least interval
+// apart so registry servers are not hammered. Registrations, and domains
+// the server does not know, are cached per registrable domain; other
+// failures are tried again by the next survivor of the domain.
+type rdapClient struct {
+	client   *http.Client
+	interval time.Duration
+
+	bootstrapMu sync.Mutex
+	servers     map[string]string // TLD -> RDAP base URL.
+
+	throttleMu sync.Mutex
+	nextQuery  map[string]time.Time // Per server, when it may be queried again.
+
+	mu    sync.Mutex
+	cache map[string]*registrationEntry
+}
+
+// newRDAPClient returns a client that goes through transport, the scan's,
+// so lookups honor the proxies and -source-ip. Certificates are always
+// verified, even under -insecure.
+func newRDAPClient(transport *http.Transport, interval, timeout time.Duration) *rdapClient {
+	transport = transport.Clone()
+	transport.TLSClientConfig = &tls.Config{}
+	return &rdapClient{
+		client:    &http.Client{Transport: transport, Timeout: timeout},
+		interval:  interval,
+		nextQuery: make(map[string]time.Time),
+		cache:     make(map[string]*registrationEntry),
+	}
+}
+
+// enrich fills in the registration of each survivor from in that asks for
+// it and passes every result on to out, closing out once in is drained.
+// Lookups run in a stage of their own, so scan workers never wait on the
+// RDAP servers unless maxPendingLookups survivors already do.
+func (c *rdapClient) enrich(in <-chan Result, out chan<- Result) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxPendingLookups)
+	for result := range in {
+		if !result.whois {
+			out <- result
+			continue
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reg, err := c.lookup(result.host)
+			if err != nil {
+				if scanCtx.Err() == nil {
+					fmt.Fprintf(console, "Error looking up registration for %s: %v\n", result.Domain, err)
+				}
+			} else {
+				result.Registrar = reg.registrar
+				if !reg.expires.IsZero() {
+					result.Expires = reg.expires.Format("2006-01-02")
+				}
+			}
+			out <- result
+			<-slots
+		}()
+	}
+	wg.Wait()
+	close(out)
+}
+
+// throttle waits until server may be queried, or the scan stops.
+func (c *rdapClient) throttle(server string) error {
+	c.throttleMu.Lock()
+	at := time.Now()
+	if next := c.nextQuery[server]; next.After(at) {
+		at = next
+	}
+	c.nextQuery[server] = at.Add(c.interval)
+	c.throttleMu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-scanCtx.Done():
+		return scanCtx.Err()
+	}
+}
+
+// lookup returns the registration of the registrable domain that host belongs to.
+func (c *rdapClient) lookup(host string) (registration, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if net.ParseIP(host) != nil {
+		return registration{}, fmt.Errorf("%s is an IP address", host)
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return registration{}, err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.cache[domain]
+	if !ok {
+		entry = &registrationEntry{ready: make(chan struct{})}
+		c.cache[domain] = entry
+	}
+	c.mu.Unlock()
+
+	if ok {
+		<-entry.ready
+		return entry.reg, entry.err
+	}
+	entry.reg, entry.err = c.query(domain)
+	if entry.err != nil && !errors.Is(entry.err, errNotRegistered) {
+		// Transient failures are not kept, so later survivors try again.
+		c.mu.Lock()
+		delete(c.cache, domain)
+		c.mu.Unlock()
+	}
+	close(entry.ready)
+	return entry.reg, entry.err
+}
+
+// query fetches the RDAP domain record from the server responsible for its TLD.
+func (c *rdapClient) query(domain string) (registration, error) {
+	servers, err := c.bootstrap()
+	if err != nil {
+		return registration{}, err
+	}
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	server, ok := servers[tld]
+	if !ok {
+		return registration{}, fmt.Errorf("no RDAP server known for .%s", tld)
+	}
+
+	if err := c.throttle(server); err != nil {
+		return registration{}, err
+	}
+	req, err := http.NewRequestWithContext(scanCtx, http.MethodGet, strings.TrimSuffix(server, "/")+"/domain/"+domain, nil)
+	if err != nil {
+		return registration{}, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return registration{}, fmt.Errorf("RDAP query failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return registration{}, errNotRegistered
+	}
+	if resp.StatusCode != http.StatusOK {
+		return registration{}, fmt.Errorf("RDAP server returned status %d", resp.StatusCode)
+	}
+
+	var record struct {
+		Events []struct {
+			Action string `json:"eventAction"`
+			Date   string `json:"eventDate"`
+		} `json:"events"`
+		Entities []struct {
+			Roles []string          `json:"roles"`
+			VCard []json.RawMessage `json:"vcardArray"`
+		} `json:"entities"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, rdapMaxBytes)).Decode(&record); err != nil {
+		return registration{}, fmt.Errorf("invalid RDAP response: %v", err)
+	}
+
+	var reg registration
+	for _, event := range record.Events {
+		if event.Action == "expiration" {
+			reg.expires, _ = time.Parse(time.RFC3339, event.Date)
+		}
+	}
+	for _, entity := range record.Entities {
+		for _, role := range entity.Roles {
+			if role == "registrar" && len(entity.VCard) == 2 {
+				reg.registrar = vcardName(entity.VCard[1])
+			}
+		}
+	}
+	return reg, nil
+}
+
+// bootstrap returns the RDAP server of each TLD, fetching the registry the
+// first time. A failed fetch is tried again by the next lookup, spaced like
+// the queries.
+func (c *rdapClient) bootstrap() (map[string]string, error) {
+	c.bootstrapMu.Lock()
+	defer c.bootstrapMu.Unlock()
+	if c.servers != nil {
+		return c.servers, nil
+	}
+	if err := c.throttle(rdapBootstrapURL); err != nil {
+		return nil, err
+	}
+	servers, err := c.fetchBootstrap()
+	if err != nil {
+		return nil, err
+	}
+	c.servers = servers
+	return servers, nil
+}
+
+// fetchBootstrap downloads the IANA RDAP bootstrap registry for domains.
+func (c *rdapClient) fetchBootstrap() (map[string]string, error) {
+	req, err := http.NewRequestWithContext(scanCtx, http.MethodGet, rdapBootstrapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch RDAP bootstrap: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch RDAP bootstrap: status %d", resp.StatusCode)
+	}
+
+	var bootstrap struct {
+		Services [][][]string `json:"services"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, rdapMaxBytes)).Decode(&bootstrap); err != nil {
+		return nil, fmt.Errorf("invalid RDAP bootstrap: %v", err)
+	}
+	servers := make(map[string]string)
+	for _, service := range bootstrap.Services {
+		if len(service) != 2 || len(service[1]) == 0 {
+			continue
+		}
+		for _, tld := range service[0] {
+			servers[strings.ToLower(tld)] = service[1][0]
+		}
+	}
+	return servers, nil
+}
+
+// vcardName returns the "fn" (formatted name) property of a jCard property list.
+func vcardName(properties json.RawMessage) string {
+	var props [][]any
+	if json.Unmarshal(properties, &props) != nil {
+		return ""
+	}
+	for _, prop := range props {
+		if len(prop) == 4 && prop[0] == "fn" {
+			if name, ok := prop[3].(string); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
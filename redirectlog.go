@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// redirectRecorder writes where dropped redirects pointed to the
+// -record-redirect-target file, one "domain status location" line each.
+type redirectRecorder struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	count  int
+}
+
+func newRedirectRecorder(path string, appendMode bool) (*redirectRecorder, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &redirectRecorder{file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+// record writes the Location of resp, resolved against the request URL when
+// relative. Redirects without a usable Location are left out.
+func (r *redirectRecorder) record(domain string, resp *http.Response) {
+	location, err := resp.Location()
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.count++
+	fmt.Fprintf(r.writer, "%s %d %s\n", domain, resp.StatusCode, location)
+}
+
+func (r *redirectRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.writer.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket is the -retry-budget shared by every retry in the scan. When
+// many targets fail at once, e.g. because a network segment went down, their
+// retries are spread out at the budget's rate instead of all firing together.
+type tokenBucket struct {
+	rate  float64 // Tokens added per second.
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	burst := max(rate, 1)
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait takes a token, blocking until one is available or ctx is done. Tokens
+// are reserved in arrival order, so waiters are served first come, first served.
+func (b *tokenBucket) wait(ctx context.Context) {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	deficit := -b.tokens
+	b.mu.Unlock()
+
+	if deficit <= 0 {
+		return
+	}
+	timer := time.NewTimer(time.Duration(deficit / b.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
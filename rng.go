@@ -0,0 +1,36 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedRand is the run's random source, shared by every goroutine. It is
+// seeded once from -seed, so the random choices of a run (the -sample
+// selection, jitter and baseline paths) can be repeated.
+type lockedRand struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// scanRand is the random source for the run, reseeded in main.
+var scanRand = newLockedRand(uint64(time.Now().UnixNano()))
+
+func newLockedRand(seed uint64) *lockedRand {
+	return &lockedRand{rand: rand.New(rand.NewSource(int64(seed)))}
+}
+
+// Int63n returns a random number in [0, n).
+func (r *lockedRand) Int63n(n int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Int63n(n)
+}
+
+// Read fills p with random bytes.
+func (r *lockedRand) Read(p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rand.Read(p)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"container/list"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// robotsAgent is the product token matched against User-agent lines in robots.txt.
+const robotsAgent = "domainsurvivor"
+
+// robotsMaxBytes caps how much of a robots.txt file is parsed, as RFC 9309 allows.
+const robotsMaxBytes = 500 << 10
+
+// robotsCacheSize is how many hosts' robots.txt rules are kept. The least
+// recently used are dropped, and fetched again if the host comes back.
+const robotsCacheSize = 10000
+
+// robotsRules holds the Allow and Disallow patterns that apply to us.
+type robotsRules struct {
+	allow, disallow []string
+	disallowAll     bool
+}
+
+// robotsEntry is a cached robots.txt lookup. ready is closed once rules is set,
+// so concurrent workers for the same host wait for a single fetch.
+type robotsEntry struct {
+	ready chan struct{}
+	rules robotsRules
+}
+
+// robotsLRU is a thread-safe LRU cache of robots.txt lookups keyed by host.
+type robotsLRU struct {
+	mu    sync.Mutex
+	order *list.List // Front is most recently used.
+	items map[string]*list.Element
+}
+
+type robotsItem struct {
+	key   string
+	entry *robotsEntry
+}
+
+var robotsCache = &robotsLRU{order: list.New(), items: make(map[string]*list.Element)}
+
+// entry returns the lookup cached for key, or a new one that the caller
+// must fill in when found is false.
+func (c *robotsLRU) entry(key string) (entry *robotsEntry, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*robotsItem).entry, true
+	}
+	entry = &robotsEntry{ready: make(chan struct{})}
+	c.items[key] = c.order.PushFront(&robotsItem{key: key, entry: entry})
+	if c.order.Len() > robotsCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*robotsItem).key)
+	}
+	return entry, false
+}
+
+// robotsAllowed reports whether path may be fetched from scheme://host,
+// fetching and caching the host's robots.txt on first use. hostHeader, when
+// set, is sent as the Host header just like the probe itself.
+func robotsAllowed(client *http.Client, scheme, host, hostHeader, path string) bool {
+	key := scheme + "://" + host + "|" + hostHeader
+
+	entry, ok := robotsCache.entry(key)
+	if ok {
+		<-entry.ready
+	} else {
+		entry.rules = fetchRobots(client, scheme, host, hostHeader)
+		close(entry.ready)
+	}
+	return entry.rules.allowed(path)
+}
+
+// fetchRobots downloads and parses robots.txt. As in RFC 9309, a 4xx allows
+// everything and a 5xx disallows everything. A network error allows the probe,
+// which then reports the underlying failure itself.
+func fetchRobots(client *http.Client, scheme, host, hostHeader string) robotsRules {
+	robotsURL := fmt.Sprintf("%s://%s/robots.txt", scheme, host)
+	req, err := http.NewRequestWithContext(scanCtx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return robotsRules{}
+	}
+	setRequestHeaders(req)
+	// Let the transport negotiate compression so the body arrives decoded.
+	req.Header.Del("Accept-Encoding")
+	if hostHeader != "" {
+		req.Host = hostHeader
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return robotsRules{}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return robotsRules{disallowAll: true}
+	case resp.StatusCode != http.StatusOK:
+		return robotsRules{}
+	}
+	return parseRobots(io.LimitReader(resp.Body, robotsMaxBytes))
+}
+
+// parseRobots extracts the rules of the group addressed to robotsAgent,
+// falling back to the "*" group when no group names us.
+func parseRobots(r io.Reader) robotsRules {
+	var ours, wildcard robotsRules
+	var foundOurs bool
+	var groupAgents []string
+	inRules := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group.
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue // An empty Disallow allows everything.
+			}
+			for _, agent := range groupAgents {
+				var rules *robotsRules
+				switch {
+				case agent == "*":
+					rules = &wildcard
+				case strings.HasPrefix(agent, robotsAgent):
+					rules = &ours
+					foundOurs = true
+				default:
+					continue
+				}
+				if key == "allow" {
+					rules.allow = append(rules.allow, value)
+				} else {
+					rules.disallow = append(rules.disallow, value)
+				}
+			}
+		}
+	}
+	if foundOurs {
+		return ours
+	}
+	return wildcard
+}
+
+// allowed applies the most specific (longest) matching rule, with Allow
+// winning ties, as described in RFC 9309.
+func (r robotsRules) allowed(path string) bool {
+	if r.disallowAll {
+		return false
+	}
+	longestAllow, longestDisallow := -1, -1
+	for _, pattern := range r.allow {
+		if robotsMatch(pattern, path) && len(pattern) > longestAllow {
+			longestAllow = len(pattern)
+		}
+	}
+	for _, pattern := range r.disallow {
+		if robotsMatch(pattern, path) && len(pattern) > longestDisallow {
+			longestDisallow = len(pattern)
+		}
+	}
+	return longestDisallow < 0 || longestAllow >= longestDisallow
+}
+
+// robotsMatch matches a robots.txt path pattern, supporting the "*" wildcard
+// and a trailing "$" end anchor.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if !anchored {
+		return true
+	}
+	// With an anchor, the last literal part must end the path.
+	last := parts[len(parts)-1]
+	return rest == "" || (len(parts) > 1 && strings.HasSuffix(path, last))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sampler picks the -sample share of input lines. Each line is kept or
+// dropped by a hash of the seed and the line, so a seed always selects the
+// same lines, whatever their order in the input.
+type sampler struct {
+	seed      uint64
+	threshold uint64 // Lines whose hash is below this are kept.
+	percent   float64
+}
+
+// newSampler parses a percentage such as "5%" or "0.5".
+func newSampler(spec string, seed uint64) (*sampler, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(spec), "%"), 64)
+	if err != nil || percent <= 0 || percent > 100 {
+		return nil, fmt.Errorf("invalid sample %q (expected a percentage such as 5%%)", spec)
+	}
+	threshold := uint64(math.MaxUint64)
+	if percent < 100 {
+		threshold = uint64(percent / 100 * math.MaxUint64)
+	}
+	return &sampler{seed: seed, threshold: threshold, percent: percent}, nil
+}
+
+// keep reports whether line is part of the sample.
+func (s *sampler) keep(line string) bool {
+	if s.threshold == math.MaxUint64 {
+		return true
+	}
+	h := fnv.New64a()
+	var seed [8]byte
+	binary.LittleEndian.PutUint64(seed[:], s.seed)
+	h.Write(seed[:])
+	h.Write([]byte(line))
+	return h.Sum64() < s.threshold
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultScoreWeights ranks answers by how interesting they usually are for
+// triage: working pages first, then protected ones, redirects and errors.
+const defaultScoreWeights = "200=100,2xx=80,401=60,403=60,3xx=40,5xx=30,4xx=10"
+
+// statusScore awards points to the status codes in set.
+type statusScore struct {
+	set    statusSet
+	points int
+}
+
+// parseScoreWeights parses a -score-weights list of status=points entries.
+// The status is anything a single -status entry accepts, and the first entry
+// naming a code gives its points, so specific codes go before their class.
+func parseScoreWeights(spec string) ([]statusScore, error) {
+	var weights []statusScore
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		status, points, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid score weight %q (expected status=points, e.g. 403=60)", field)
+		}
+		set, err := parseStatusSet(status)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(points))
+		if err != nil {
+			return nil, fmt.Errorf("invalid points in score weight %q", field)
+		}
+		weights = append(weights, statusScore{set: set, points: n})
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("-score-weights needs at least one status=points entry")
+	}
+	return weights, nil
+}
+
+// responseScore returns the points of the first weight naming code, or 0.
+func responseScore(code int) int {
+	for _, w := range scoreWeights {
+		if w.set.contains(code) {
+			return w.points
+		}
+	}
+	return 0
+}
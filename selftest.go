@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// runSelfTest checks DNS resolution, a direct fetch of controlURL and a fetch
+// through every configured proxy, printing a pass/fail line for each. It
+// reports whether every check passed.
+func runSelfTest(controlURL string) bool {
+	passed := true
+	report := func(ok bool, check, detail string) {
+		status := "PASS"
+		if !ok {
+			status = "FAIL"
+			passed = false
+		}
+		fmt.Fprintf(console, "[%s] %-28s %s\n", status, check, detail)
+	}
+
+	control, err := url.Parse(controlURL)
+	if err != nil || control.Hostname() == "" {
+		report(false, "control URL", fmt.Sprintf("invalid URL %q", controlURL))
+		return false
+	}
+
+	resolver := "system resolver"
+	var addrs []string
+	if doh != nil {
+		resolver = "DoH " + doh.endpoint
+		addrs, err = doh.lookupHost(context.Background(), control.Hostname())
+	} else {
+		addrs, err = net.DefaultResolver.LookupHost(context.Background(), control.Hostname())
+	}
+	if err != nil {
+		report(false, "DNS ("+resolver+")", err.Error())
+	} else {
+		report(true, "DNS ("+resolver+")", control.Hostname()+" -> "+strings.Join(addrs, ", "))
+	}
+
+	directIP, err := selfTestFetch(controlURL, nil)
+	if err != nil {
+		report(false, "direct", err.Error())
+	} else {
+		report(true, "direct", "IP "+directIP)
+	}
+
+	if len(proxies) == 0 {
+		if env := os.Getenv("HTTPS_PROXY") + os.Getenv("HTTP_PROXY"); env != "" {
+			fmt.Fprintln(console, "No proxies configured; requests will use HTTP_PROXY/HTTPS_PROXY from the environment.")
+		} else {
+			fmt.Fprintln(console, "No proxies configured; requests will be made directly.")
+		}
+		return passed
+	}
+	ips := make(map[string]int)
+	working := 0
+	for _, entry := range proxies {
+		proxyURL, err := parseProxyURL(entry)
+		if err != nil {
+			report(false, "proxy", err.Error())
+			continue
+		}
+		name := "proxy " + proxyURL.Redacted()
+		ip, err := selfTestFetch(controlURL, proxyURL)
+		switch {
+		case err != nil:
+			report(false, name, err.Error())
+		case ip == directIP:
+			report(false, name, "IP "+ip+" is the direct IP; the proxy is not in use")
+		default:
+			report(true, name, "IP "+ip)
+			ips[ip]++
+			working++
+		}
+	}
+	if working > 0 {
+		report(len(ips) == working, "proxy rotation",
+			fmt.Sprintf("%d distinct IPs across %d working proxies", len(ips), working))
+	}
+	return passed
+}
+
+// selfTestFetch fetches controlURL directly, or through proxyURL when set,
+// and returns the IP it reports: the "ip" field of a JSON body, or the whole
+// body trimmed otherwise.
+func selfTestFetch(controlURL string, proxyURL *url.URL) (string, error) {
+	transport := httpClient.Transport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	if proxyURL != nil {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	client := &http.Client{Transport: transport, Timeout: httpClient.Timeout}
+
+	resp, err := client.Get(controlURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	var reply struct {
+		IP string `json:"ip"`
+	}
+	if json.Unmarshal(body, &reply) == nil && reply.IP != "" {
+		return reply.IP, nil
+	}
+	return strings.TrimSpace(string(body)), nil
+}
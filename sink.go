@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stdout is where survivors go for "-o -", while console moves to stderr.
+var stdout = os.Stdout
+
+// survivorColor colors survivors written to stdout when it is a terminal;
+// files are never colored.
+var survivorColor string
+
+// OutputSink receives every result the writer goroutine emits. Sinks are only
+// called from that goroutine, so implementations need no locking.
+type OutputSink interface {
+	Write(Result) error
+	Close() error
+}
+
+// newOutputSink builds the sink selected by -sink. A positive maxSize
+// rotates file output once it grows past that many bytes, and appendMode
+// keeps the existing contents of the output file.
+func newOutputSink(kind, path, webhookURL string, asJSON, stream, appendMode bool, maxSize int64) (OutputSink, error) {
+	switch kind {
+	case "file":
+		sink, err := newFileSink(path, asJSON, stream, appendMode)
+		if err != nil {
+			return nil, err
+		}
+		if path != "-" {
+			sink.maxSize = maxSize
+		}
+		return sink, nil
+	case "webhook":
+		if webhookURL == "" {
+			return nil, fmt.Errorf("-sink webhook requires -webhook-url")
+		}
+		return newWebhookSink(webhookURL), nil
+	case "log":
+		return newLogSink(path)
+	case "sqlite":
+		return newSQLiteSink(path, appendMode)
+	default:
+		return nil, fmt.Errorf("unknown sink %q (expected file, webhook, log or sqlite)", kind)
+	}
+}
+
+// fileSink writes plain or JSON lines to a file, or to stdout for "-".
+type fileSink struct {
+	path    string
+	file    *os.File
+	counter *countingWriter
+	writer  *bufio.Writer
+	asJSON  bool
+	stream  bool
+	// Rotate to path.1, path.2, ... once the file exceeds maxSize bytes (0 never rotates).
+	maxSize int64
+	// Offsets of the records written, under -index; nil when unset.
+	index *outputIndex
+}
+
+// newFileSink creates the file at path, or with appendMode adds to it.
+func newFileSink(path string, asJSON, stream, appendMode bool) (*fileSink, error) {
+	file := stdout
+	counter := &countingWriter{w: file}
+	if path != "-" {
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if appendMode {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		var err error
+		file, err = os.OpenFile(path, flags, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		counter.w = file
+		// Appended survivors count toward -max-output-size.
+		if info, err := file.Stat(); err == nil {
+			counter.n = info.Size()
+		}
+	}
+	return &fileSink{path: path, file: file, counter: counter, writer: bufio.NewWriter(counter), asJSON: asJSON, stream: stream}, nil
+}
+
+func (s *fileSink) Write(r Result) error {
+	color := ""
+	if s.file == stdout {
+		color = survivorColor
+	}
+	start := s.counter.n + int64(s.writer.Buffered())
+	if err := writeResult(s.writer, r, s.asJSON, s.stream, color); err != nil {
+		return err
+	}
+	if s.index != nil {
+		s.index.add(start, s.counter.n+int64(s.writer.Buffered()))
+		if err := s.index.sync(s.counter.n); err != nil {
+			return fmt.Errorf("writing index: %v", err)
+		}
+	}
+	if s.maxSize > 0 && s.counter.n+int64(s.writer.Buffered()) >= s.maxSize {
+		return s.rotate()
+	}
+	return nil
+}
+
+// rotate flushes and closes the current file, renames it to the first free
+// path.N and starts a new file at path. The writer goroutine is the only
+// caller, so no result is written in between.
+func (s *fileSink) rotate() error {
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	for n := 1; ; n++ {
+		rotated := fmt.Sprintf("%s.%d", s.path, n)
+		if _, err := os.Stat(rotated); err == nil {
+			continue
+		}
+		if err := os.Rename(s.path, rotated); err != nil {
+			return err
+		}
+		break
+	}
+	file, err := os.Create(s.path)
+	if err != nil {
+		return err
+	}
+	s.file = file
+	s.counter = &countingWriter{w: file}
+	s.writer.Reset(s.counter)
+	return nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// parseSize parses a byte size such as 500000, 64KB, 100MB or 2GB (powers of 1024).
+func parseSize(s string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(upper, unit.suffix) {
+			upper = strings.TrimSuffix(upper, unit.suffix)
+			multiplier = unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(upper), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
+
+func (s *fileSink) Close() error {
+	err := s.writer.Flush()
+	if s.index != nil {
+		if err == nil {
+			err = s.index.sync(s.counter.n)
+		}
+		if closeErr := s.index.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if s.file != stdout {
+		if closeErr := s.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// webhookSink POSTs each result as JSON to a collector URL.
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookSink(url string) *webhookSink {
+	return &webhookSink{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *webhookSink) Write(r Result) error {
+	data, err := marshalResult(r)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("webhook POST failed: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	return nil
+}
+
+// logSink appends JSON lines to a file that is never truncated, syncing each
+// record to disk so a crash loses at most the record being written.
+type logSink struct {
+	file *os.File
+}
+
+func newLogSink(path string) (*logSink, error) {
+	if path == "-" {
+		return nil, fmt.Errorf("-sink log needs an output file path")
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &logSink{file: file}, nil
+}
+
+func (s *logSink) Write(r Result) error {
+	data, err := marshalResult(r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *logSink) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// sortChunkSize is how many results -sorted-output keeps in memory before
+// spilling a sorted run to a temporary file.
+const sortChunkSize = 100000
+
+// sortedItem is a result with the input position it is ordered by.
+type sortedItem struct {
+	Seq    int    `json:"seq"`
+	Result Result `json:"result"`
+}
+
+// sortedSink buffers results and writes them to the wrapped sink in sorted
+// order on Close. Memory is bounded by spilling sorted runs of
+// sortChunkSize results to temporary files, which Close merges.
+type sortedSink struct {
+	inner OutputSink
+	less  func(a, b sortedItem) bool
+	chunk []sortedItem
+	runs  []*os.File
+}
+
+// newSortedSink wraps inner for -sorted-output: "input" keeps the order of
+// the input file, "alpha" sorts by domain.
+func newSortedSink(inner OutputSink, by string) (*sortedSink, error) {
+	s := &sortedSink{inner: inner}
+	switch by {
+	case "input":
+		s.less = func(a, b sortedItem) bool { return a.Seq < b.Seq }
+	case "alpha":
+		s.less = func(a, b sortedItem) bool {
+			if a.Result.Domain != b.Result.Domain {
+				return a.Result.Domain < b.Result.Domain
+			}
+			return a.Seq < b.Seq
+		}
+	default:
+		return nil, fmt.Errorf("unknown sort order %q (expected input or alpha)", by)
+	}
+	return s, nil
+}
+
+func (s *sortedSink) Write(r Result) error {
+	s.chunk = append(s.chunk, sortedItem{Seq: r.seq, Result: r})
+	if len(s.chunk) >= sortChunkSize {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill sorts the in-memory chunk and writes it to a temporary file.
+func (s *sortedSink) spill() error {
+	s.sortChunk()
+	file, err := os.CreateTemp("", "domainsurvivor-sort-*")
+	if err != nil {
+		return err
+	}
+	os.Remove(file.Name()) // Unlinked now; the open handle keeps the data.
+	s.runs = append(s.runs, file)
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, item := range s.chunk {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	s.chunk = s.chunk[:0]
+	return w.Flush()
+}
+
+func (s *sortedSink) sortChunk() {
+	sort.Slice(s.chunk, func(i, j int) bool { return s.less(s.chunk[i], s.chunk[j]) })
+}
+
+// Close merges the spilled runs with the in-memory chunk into the wrapped
+// sink. A run that cannot be read back fails it, rather than ending early.
+func (s *sortedSink) Close() error {
+	err := s.merge()
+	for _, file := range s.runs {
+		file.Close()
+	}
+	if closeErr := s.inner.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (s *sortedSink) merge() error {
+	s.sortChunk()
+	h := &mergeHeap{less: s.less}
+	var sources []*runSource
+	for _, file := range s.runs {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		sources = append(sources, &runSource{dec: json.NewDecoder(bufio.NewReader(file))})
+	}
+	sources = append(sources, &runSource{chunk: s.chunk})
+	for _, src := range sources {
+		ok, err := src.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.sources = append(h.sources, src)
+		}
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		src := h.sources[0]
+		if err := s.inner.Write(src.item.Result); err != nil {
+			return err
+		}
+		ok, err := src.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return nil
+}
+
+// runSource yields sorted items from a spilled run or the in-memory chunk.
+type runSource struct {
+	dec   *json.Decoder
+	chunk []sortedItem
+	item  sortedItem
+}
+
+// next moves to the following item, reporting false at the end of the run.
+func (r *runSource) next() (bool, error) {
+	if r.dec == nil {
+		if len(r.chunk) == 0 {
+			return false, nil
+		}
+		r.item, r.chunk = r.chunk[0], r.chunk[1:]
+		return true, nil
+	}
+	r.item = sortedItem{}
+	switch err := r.dec.Decode(&r.item); err {
+	case nil:
+		return true, nil
+	case io.EOF:
+		return false, nil
+	default:
+		return false, fmt.Errorf("corrupt sorted run: %v", err)
+	}
+}
+
+// mergeHeap orders run sources by their current item.
+type mergeHeap struct {
+	sources []*runSource
+	less    func(a, b sortedItem) bool
+}
+
+func (h *mergeHeap) Len() int           { return len(h.sources) }
+func (h *mergeHeap) Less(i, j int) bool { return h.less(h.sources[i].item, h.sources[j].item) }
+func (h *mergeHeap) Swap(i, j int)      { h.sources[i], h.sources[j] = h.sources[j], h.sources[i] }
+func (h *mergeHeap) Push(x any)         { h.sources = append(h.sources, x.(*runSource)) }
+func (h *mergeHeap) Pop() any {
+	last := h.sources[len(h.sources)-1]
+	h.sources = h.sources[:len(h.sources)-1]
+	return last
+}
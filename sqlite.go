@@ -0,0 +1,130 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sqliteBatchSize is how many survivors the SQLite sink inserts per transaction.
+const sqliteBatchSize = 500
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS survivors (
+	id INTEGER PRIMARY KEY,
+	domain TEXT NOT NULL,
+	url TEXT,
+	scheme TEXT,
+	status INTEGER,
+	title TEXT,
+	response_time_ms REAL,
+	body_sha256 TEXT,
+	technologies TEXT,
+	found_at TEXT NOT NULL,
+	record TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS survivors_domain ON survivors (domain);`
+
+// sqliteSink inserts survivors into the survivors table of a SQLite
+// database, batching them into transactions. record holds the full JSON
+// result, for fields without a column of their own.
+type sqliteSink struct {
+	db      *sql.DB
+	pending []Result
+}
+
+// newSQLiteSink opens or creates the database at path. The rows of earlier
+// runs are removed unless appendMode is set.
+func newSQLiteSink(path string, appendMode bool) (*sqliteSink, error) {
+	if !sqliteSupported {
+		return nil, fmt.Errorf("this build has no SQLite support; rebuild with go build -tags sqlite to use it")
+	}
+	if path == "-" {
+		return nil, fmt.Errorf("-sink sqlite needs a database file path")
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// One connection, so the whole scan uses a single writer.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating survivors table: %v", err)
+	}
+	if !appendMode {
+		if _, err := db.Exec("DELETE FROM survivors"); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &sqliteSink{db: db}, nil
+}
+
+func (s *sqliteSink) Write(r Result) error {
+	s.pending = append(s.pending, r)
+	if len(s.pending) < sqliteBatchSize {
+		return nil
+	}
+	return s.flush()
+}
+
+// flush inserts the pending survivors in one transaction.
+func (s *sqliteSink) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO survivors
+		(domain, url, scheme, status, title, response_time_ms, body_sha256, technologies, found_at, record)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	now := time.Now().Format(time.RFC3339)
+	for _, r := range s.pending {
+		record, err := json.Marshal(r)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		scheme := ""
+		if len(r.Protocols) > 0 {
+			scheme = r.Protocols[0]
+		}
+		foundAt := r.FoundAt
+		if foundAt == "" {
+			foundAt = now
+		}
+		if _, err := stmt.Exec(r.Domain, nullString(r.URL), nullString(scheme), sql.NullInt64{Int64: int64(r.StatusCode), Valid: r.StatusCode != 0},
+			nullString(r.Title), sql.NullFloat64{Float64: r.ResponseTimeMs, Valid: r.ResponseTimeMs != 0}, nullString(r.BodyHash),
+			nullString(strings.Join(r.Technologies, ",")), foundAt, string(record)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.pending = s.pending[:0]
+	return nil
+}
+
+// nullString stores empty strings as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func (s *sqliteSink) Close() error {
+	err := s.flush()
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,9 @@
+//go:build sqlite
+
+package main
+
+// The pure-Go SQLite driver, registered as "sqlite".
+import _ "modernc.org/sqlite"
+
+// sqliteSupported is set in builds with the sqlite tag, which link the driver.
+const sqliteSupported = true
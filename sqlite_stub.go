@@ -0,0 +1,7 @@
+//go:build !sqlite
+
+package main
+
+// sqliteSupported is unset in default builds, which leave out the SQLite
+// driver; build with -tags sqlite for -sink sqlite.
+const sqliteSupported = false
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// statusAliases are the named status groups accepted by -status.
+var statusAliases = map[string]string{
+	"success":       "2xx",
+	"redirects":     "3xx",
+	"client-errors": "4xx",
+	"server-errors": "5xx",
+	"errors":        "4xx,5xx",
+}
+
+// statusSet is the set of status codes a survivor may answer with, parsed
+// from a -status list such as "200,301-308,4xx,redirects".
+type statusSet struct {
+	spec   string
+	ranges [][2]int // Inclusive bounds.
+}
+
+// parseStatusSet parses comma-separated codes, ranges (301-399), classes
+// (1xx to 5xx) and the names in statusAliases.
+func parseStatusSet(spec string) (statusSet, error) {
+	set := statusSet{spec: spec}
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if alias, ok := statusAliases[field]; ok {
+			sub, err := parseStatusSet(alias)
+			if err != nil {
+				return statusSet{}, err
+			}
+			set.ranges = append(set.ranges, sub.ranges...)
+			continue
+		}
+		if len(field) == 3 && strings.HasSuffix(field, "xx") && field[0] >= '1' && field[0] <= '5' {
+			class := int(field[0]-'0') * 100
+			set.ranges = append(set.ranges, [2]int{class, class + 99})
+			continue
+		}
+		low, high, isRange := strings.Cut(field, "-")
+		if !isRange {
+			high = low
+		}
+		from, err1 := strconv.Atoi(low)
+		to, err2 := strconv.Atoi(high)
+		if err1 != nil || err2 != nil || from < 100 || to > 599 || from > to {
+			return statusSet{}, fmt.Errorf("invalid status %q (expected a code such as 200, a range such as 301-399, a class such as 4xx, or one of success, redirects, client-errors, server-errors, errors)", field)
+		}
+		set.ranges = append(set.ranges, [2]int{from, to})
+	}
+	if len(set.ranges) == 0 {
+		return statusSet{}, fmt.Errorf("-status needs at least one status code")
+	}
+	return set, nil
+}
+
+// singleStatus is the set holding only code.
+func singleStatus(code int) statusSet {
+	return statusSet{spec: strconv.Itoa(code), ranges: [][2]int{{code, code}}}
+}
+
+func (s statusSet) contains(code int) bool {
+	for _, r := range s.ranges {
+		if code >= r[0] && code <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s statusSet) String() string {
+	return s.spec
+}
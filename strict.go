@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// strictProblems lists the flag combinations that -strict refuses to run
+// with: settings that have no effect, or that leave nothing to survive.
+// Each message names the flags involved and how to fix them.
+func strictProblems() []string {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	value := func(name string) string { return flag.Lookup(name).Value.String() }
+	enabled := func(name string) bool { return value(name) == "true" }
+
+	var problems []string
+	ignored := func(name, because string) {
+		problems = append(problems, fmt.Sprintf("-%s has no effect %s; remove it or fix the other setting", name, because))
+	}
+	if set["baseline-mode"] && value("baseline-threshold") == "0" {
+		ignored("baseline-mode", "without -baseline-threshold (e.g. -baseline-threshold 0.9)")
+	}
+	if set["status"] && (enabled("alive") || value("match-expr") != "" || value("min-score") != "0") {
+		ignored("status", "with -alive, -match-expr or -min-score, which replace it")
+	}
+	if enabled("alive") && (value("match-expr") != "" || value("min-score") != "0") {
+		ignored("alive", "with -match-expr or -min-score, which replace it")
+	}
+	if set["score-weights"] && value("min-score") == "0" {
+		ignored("score-weights", "without -min-score")
+	}
+	if enabled("alive-malformed") && !enabled("alive") {
+		ignored("alive-malformed", "without -alive")
+	}
+	if enabled("only-offsite-redirects") && (enabled("drop-redirects") || value("max-redirects") == "0") {
+		problems = append(problems, "-only-offsite-redirects keeps nothing when redirects are not followed; "+
+			"remove -drop-redirects or raise -max-redirects")
+	}
+	if set["record-redirect-target"] && !enabled("drop-redirects") {
+		ignored("record-redirect-target", "without -drop-redirects")
+	}
+	if enabled("warmup") && (enabled("no-estimate") || value("estimate-sample") == "0") {
+		ignored("warmup", "without an estimate sample (set -estimate-sample, and not -no-estimate)")
+	}
+	if set["probe-timeout-retry-once"] && enabled("tcp-only") {
+		ignored("probe-timeout-retry-once", "with -tcp-only, whose connects keep -timeout")
+	}
+	if enabled("min-tls-success") && enabled("tcp-only") {
+		ignored("min-tls-success", "with -tcp-only, which does no TLS handshake")
+	}
+	if enabled("alt-svc") && enabled("tcp-only") {
+		ignored("alt-svc", "with -tcp-only, which sends no HTTP requests")
+	}
+	if value("http3") != "off" && enabled("tcp-only") {
+		ignored("http3", "with -tcp-only, which sends no HTTP requests")
+	}
+	if enabled("case-probe") && enabled("tcp-only") {
+		ignored("case-probe", "with -tcp-only, which sends no HTTP requests")
+	}
+	if set["seed"] && value("sample") == "" && value("delay-jitter") == "0s" && value("ramp-up") == "0s" &&
+		value("baseline-threshold") == "0" && !enabled("case-probe") {
+		ignored("seed", "without -sample, -delay-jitter, -ramp-up, -baseline-threshold or -case-probe")
+	}
+	if set["dedup-fp-rate"] && value("dedup") != "bloom" {
+		ignored("dedup-fp-rate", "unless -dedup is bloom")
+	}
+	if set["drift-db"] && !enabled("only-changed") {
+		ignored("drift-db", "without -only-changed")
+	}
+	if set["exec-concurrency"] && value("exec") == "" {
+		ignored("exec-concurrency", "without -exec")
+	}
+	if set["whois-interval"] && !enabled("whois") {
+		ignored("whois-interval", "without -whois")
+	}
+	if set["webhook-url"] && value("sink") != "webhook" {
+		ignored("webhook-url", "unless -sink is webhook")
+	}
+	if enabled("show-scheme") && (enabled("json") || value("sink") != "file") {
+		ignored("show-scheme", "on JSON output, which has the protocols field")
+	}
+	if set["headers-file"] && (!enabled("headers-out") || enabled("json") || value("sink") != "file") {
+		ignored("headers-file", "without -headers-out on plain output; JSON records carry the headers field")
+	}
+	if enabled("show-status") && (enabled("json") || value("sink") != "file") {
+		ignored("show-status", "on JSON output, which has the status field")
+	}
+	return problems
+}
+
+// checkProxyHealth fetches controlURL through every configured proxy and
+// returns an error when none of them works, since every probe would fail.
+func checkProxyHealth(controlURL string) error {
+	failed := 0
+	for _, entry := range proxies {
+		proxyURL, err := parseProxyURL(entry)
+		if err != nil {
+			fmt.Fprintf(console, "Proxy failed the health check: %v\n", err)
+			failed++
+			continue
+		}
+		if _, err := selfTestFetch(controlURL, proxyURL); err != nil {
+			fmt.Fprintf(console, "Proxy %s failed the health check: %v\n", proxyURL.Redacted(), err)
+			failed++
+		}
+	}
+	if failed > 0 && failed == len(proxies) {
+		return fmt.Errorf("all %d proxies failed the health check against %s; fix them or check -selftest-url", failed, controlURL)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Latency histogram layout: bucket i holds durations up to
+// histogramBase * histogramGrowth^i, giving about 10% precision from 100µs
+// to several minutes in a fixed amount of memory.
+const (
+	histogramBase    = 100 * time.Microsecond
+	histogramGrowth  = 1.1
+	histogramBuckets = 160
+)
+
+// latencyHistogram is a lock-free, fixed-size histogram of durations.
+type latencyHistogram struct {
+	buckets [histogramBuckets]atomic.Int64
+	count   atomic.Int64
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	i := 0
+	if d > histogramBase {
+		i = int(math.Ceil(math.Log(float64(d)/float64(histogramBase)) / math.Log(histogramGrowth)))
+	}
+	if i >= histogramBuckets {
+		i = histogramBuckets - 1
+	}
+	h.buckets[i].Add(1)
+	h.count.Add(1)
+}
+
+// percentile returns the upper bound of the bucket holding the p-th percentile.
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	total := h.count.Load()
+	if total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p / 100 * float64(total)))
+	var seen int64
+	for i := range h.buckets {
+		seen += h.buckets[i].Load()
+		if seen >= rank {
+			return time.Duration(float64(histogramBase) * math.Pow(histogramGrowth, float64(i)))
+		}
+	}
+	return 0
+}
+
+// traceStats aggregates httptrace phase timings across all requests under -trace.
+type traceStats struct {
+	dns, connect, tls, ttfb latencyHistogram
+	reused, fresh           atomic.Int64
+}
+
+// requestTrace holds the start times of one request's phases. Connect
+// callbacks can run concurrently when dialing several addresses.
+type requestTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart map[string]time.Time
+	tlsStart     time.Time
+}
+
+// attach returns req with a ClientTrace recording its phases into s.
+func (s *traceStats) attach(req *http.Request) *http.Request {
+	rt := &requestTrace{start: time.Now(), connectStart: make(map[string]time.Time)}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			rt.mu.Lock()
+			rt.dnsStart = time.Now()
+			rt.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			rt.mu.Lock()
+			s.dns.record(time.Since(rt.dnsStart))
+			rt.mu.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			rt.mu.Lock()
+			rt.connectStart[addr] = time.Now()
+			rt.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			rt.mu.Lock()
+			if err == nil {
+				s.connect.record(time.Since(rt.connectStart[addr]))
+			}
+			rt.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			rt.mu.Lock()
+			rt.tlsStart = time.Now()
+			rt.mu.Unlock()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			rt.mu.Lock()
+			if err == nil {
+				s.tls.record(time.Since(rt.tlsStart))
+			}
+			rt.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				s.reused.Add(1)
+			} else {
+				s.fresh.Add(1)
+			}
+		},
+		GotFirstResponseByte: func() {
+			s.ttfb.record(time.Since(rt.start))
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// print writes the phase percentiles and connection reuse counts.
+func (s *traceStats) print() {
+	fmt.Fprintln(console, "Request phase timings (p50 / p90 / p99):")
+	for _, phase := range []struct {
+		name string
+		h    *latencyHistogram
+	}{
+		{"dns", &s.dns},
+		{"connect", &s.connect},
+		{"tls", &s.tls},
+		{"first byte", &s.ttfb},
+	} {
+		if phase.h.count.Load() == 0 {
+			fmt.Fprintf(console, "  %-10s no samples\n", phase.name)
+			continue
+		}
+		fmt.Fprintf(console, "  %-10s %v / %v / %v (%d samples)\n", phase.name,
+			phase.h.percentile(50).Round(time.Microsecond*100),
+			phase.h.percentile(90).Round(time.Microsecond*100),
+			phase.h.percentile(99).Round(time.Microsecond*100),
+			phase.h.count.Load())
+	}
+	fmt.Fprintf(console, "Connections: %d new, %d reused\n", s.fresh.Load(), s.reused.Load())
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// trailingDotMode is how -normalize-trailing-dot treats fully-qualified
+// input names such as "example.com.": "off" uses them as given, "host"
+// drops the dot from the Host header while DNS lookups keep it, and "all"
+// drops it everywhere.
+var trailingDotMode = "off"
+
+// withoutTrailingDot returns host, with an optional port, without the
+// trailing dot of a fully-qualified name.
+func withoutTrailingDot(host string) string {
+	if name, port, err := net.SplitHostPort(host); err == nil {
+		if strings.HasSuffix(name, ".") {
+			return net.JoinHostPort(strings.TrimSuffix(name, "."), port)
+		}
+		return host
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// normalizeTrailingDot applies -normalize-trailing-dot to t. A name with a
+// trailing dot is absolute in DNS, so it is looked up as is rather than
+// through the resolver's search domains, but servers rarely configure
+// virtual hosts for it, and a Host header carrying the dot often hits the
+// default site instead. The TLS server name never carries the dot.
+func normalizeTrailingDot(t target) target {
+	switch trailingDotMode {
+	case "host":
+		if t.hostHeader == "" && withoutTrailingDot(t.host) != t.host {
+			t.hostHeader = withoutTrailingDot(t.host)
+		}
+		t.sni, t.hostHeader = withoutTrailingDot(t.sni), withoutTrailingDot(t.hostHeader)
+	case "all":
+		t.host, t.sni, t.hostHeader = withoutTrailingDot(t.host), withoutTrailingDot(t.sni), withoutTrailingDot(t.hostHeader)
+	}
+	return t
+}